@@ -0,0 +1,260 @@
+package jrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/daichitakahashi/jrpc/internal/pending"
+)
+
+/*
+TODO:
+
+*/
+
+// BalanceStrategy determines which endpoint of Balancer is used for each call.
+type BalanceStrategy int
+
+const (
+	// RoundRobin uses endpoints in turn.
+	RoundRobin BalanceStrategy = iota
+	// LeastPending uses the endpoint that has the least calls in flight.
+	LeastPending
+)
+
+// errNoEndpoint is returned when Balancer has no endpoint.
+var errNoEndpoint = errors.New("jrpc: no endpoint in Balancer")
+
+// errBalancerRoundTripOnly is returned by SendRequest and ReceivedResponse of Balancer.
+var errBalancerRoundTripOnly = errors.New("jrpc: Balancer supports only RoundTrip, use it through Client")
+
+type (
+	balancerOptions struct {
+		strategy      BalanceStrategy
+		maxFailures   int
+		ejectDuration time.Duration
+	}
+
+	// BalancerOption is
+	BalancerOption interface {
+		applyBalancer(opts *balancerOptions)
+	}
+
+	balancerOptionFunc func(opts *balancerOptions)
+)
+
+func (bof balancerOptionFunc) applyBalancer(opts *balancerOptions) {
+	bof(opts)
+}
+
+var defaultBalancerOptions = balancerOptions{
+	strategy:      RoundRobin,
+	maxFailures:   3,
+	ejectDuration: 10 * time.Second,
+}
+
+// WithBalanceStrategy is
+// Default is RoundRobin.
+func WithBalanceStrategy(strategy BalanceStrategy) BalancerOption {
+	return balancerOptionFunc(func(opts *balancerOptions) {
+		opts.strategy = strategy
+	})
+}
+
+// WithPassiveEjection sets the number of consecutive transport errors to eject the endpoint,
+// such as connection failure and HTTP 5xx status,
+// and the duration until the ejected endpoint is used again.
+// Default is 3 failures and 10 seconds. If maxFailures <= 0, endpoints are never ejected.
+func WithPassiveEjection(maxFailures int, duration time.Duration) BalancerOption {
+	return balancerOptionFunc(func(opts *balancerOptions) {
+		opts.maxFailures = maxFailures
+		opts.ejectDuration = duration
+	})
+}
+
+type (
+	// Balancer is CallTransport that distributes calls over multiple endpoints.
+	// When a call fails with transport error, it is sent to another endpoint (failover).
+	// The errors rejecting the request itself, such as HTTP 4xx status except 408 and 429 (see StatusCoder),
+	// are returned without failover, and not counted as failures of the endpoint.
+	// Note that the failed call might have been executed by the server.
+	Balancer struct {
+		endpoints []*endpoint
+		options   balancerOptions
+		next      uint32
+		mu        sync.Mutex // for health of endpoints
+	}
+
+	endpoint struct {
+		transport    CallTransport
+		pending      int32
+		failures     int
+		ejectedUntil time.Time
+	}
+)
+
+// NewBalancer is
+func NewBalancer(transports []CallTransport, opts ...BalancerOption) *Balancer {
+	b := &Balancer{
+		endpoints: make([]*endpoint, len(transports)),
+		options:   defaultBalancerOptions,
+	}
+	for i, t := range transports {
+		b.endpoints[i] = &endpoint{
+			transport: t,
+		}
+	}
+	for _, opt := range opts {
+		opt.applyBalancer(&b.options)
+	}
+	return b
+}
+
+// RoundTrip implements CallTransport.
+func (b *Balancer) RoundTrip(ctx context.Context, r io.Reader, ids []ID) (io.ReadCloser, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return b.roundTrip(ctx, data, ids)
+}
+
+// roundTrip sends data to healthy endpoint with failover.
+// Hedged copies of a call are sent to the endpoints that are not used by the others if possible.
+func (b *Balancer) roundTrip(ctx context.Context, data []byte, ids []ID) (io.ReadCloser, error) {
+	tried := make(map[*endpoint]bool, len(b.endpoints))
+	group, _ := ctx.Value(hedgeGroupKey{}).(*hedgeGroup)
+	if group != nil {
+		group.exclude(tried)
+	}
+	lastErr := errNoEndpoint
+	for {
+		ep := b.pick(tried)
+		if ep == nil && group != nil {
+			group = nil // all endpoints are used by hedged calls
+			tried = make(map[*endpoint]bool, len(b.endpoints))
+			ep = b.pick(tried)
+		}
+		if ep == nil {
+			return nil, lastErr
+		}
+		tried[ep] = true
+		if group != nil {
+			group.use(ep)
+		}
+
+		atomic.AddInt32(&ep.pending, 1)
+		rc, err := ep.transport.RoundTrip(ctx, bytes.NewReader(data), ids)
+		atomic.AddInt32(&ep.pending, -1)
+		if err == nil {
+			b.report(ep, true)
+			return rc, nil
+		} else if ctx.Err() != nil {
+			return nil, err
+		} else if errors.Is(err, pending.ErrDuplicateID) {
+			return nil, err // not sent
+		} else if rejected(err) {
+			b.report(ep, true) // the endpoint is alive, but the request is wrong
+			return nil, err
+		}
+		b.report(ep, false)
+		lastErr = err
+	}
+}
+
+// pick selects endpoint not in tried. Ejected endpoints are used only when all the others are tried.
+func (b *Balancer) pick(tried map[*endpoint]bool) *endpoint {
+	n := len(b.endpoints)
+	if n == 0 {
+		return nil
+	}
+	start := int(atomic.AddUint32(&b.next, 1)-1) % n
+	now := time.Now()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var picked, fallback *endpoint
+	for i := 0; i < n; i++ {
+		ep := b.endpoints[(start+i)%n]
+		if tried[ep] {
+			continue
+		}
+		if now.Before(ep.ejectedUntil) {
+			// the endpoint that will recover first
+			if fallback == nil || ep.ejectedUntil.Before(fallback.ejectedUntil) {
+				fallback = ep
+			}
+			continue
+		}
+		if b.options.strategy == RoundRobin {
+			return ep
+		}
+		if picked == nil || atomic.LoadInt32(&ep.pending) < atomic.LoadInt32(&picked.pending) {
+			picked = ep
+		}
+	}
+	if picked != nil {
+		return picked
+	}
+	return fallback
+}
+
+// report records the result of the call, and ejects the endpoint that failed consecutively.
+func (b *Balancer) report(ep *endpoint, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if ok {
+		ep.failures = 0
+		ep.ejectedUntil = time.Time{}
+		return
+	}
+	ep.failures++
+	if b.options.maxFailures > 0 && ep.failures >= b.options.maxFailures {
+		ep.ejectedUntil = time.Now().Add(b.options.ejectDuration)
+		ep.failures = 0
+	}
+}
+
+// Healthy returns the number of endpoints that are not ejected.
+func (b *Balancer) Healthy() int {
+	now := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var healthy int
+	for _, ep := range b.endpoints {
+		if !now.Before(ep.ejectedUntil) {
+			healthy++
+		}
+	}
+	return healthy
+}
+
+// SendRequest is not supported. Balancer is used by Client through RoundTrip.
+func (b *Balancer) SendRequest(context.Context, io.Reader) error {
+	return errBalancerRoundTripOnly
+}
+
+// ReceivedResponse is not supported. Balancer is used by Client through RoundTrip.
+func (b *Balancer) ReceivedResponse(context.Context) (recv io.ReadCloser, updated, shouldClose bool, err error) {
+	return nil, false, false, errBalancerRoundTripOnly
+}
+
+// Close closes all endpoints.
+func (b *Balancer) Close() error {
+	var err error
+	for _, ep := range b.endpoints {
+		if cerr := ep.transport.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+var _ CallTransport = (*Balancer)(nil)
@@ -0,0 +1,185 @@
+package jrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/daichitakahashi/jrpc/internal/pending"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEndpoint is CallTransport that responds its name as result.
+type fakeEndpoint struct {
+	name  string
+	fail  bool
+	err   error // returned instead of response if set
+	delay time.Duration
+	mu    sync.Mutex
+	calls int
+}
+
+func (fe *fakeEndpoint) RoundTrip(ctx context.Context, r io.Reader, ids []ID) (io.ReadCloser, error) {
+	fe.mu.Lock()
+	fe.calls++
+	fail, err := fe.fail, fe.err
+	fe.mu.Unlock()
+	ioutil.ReadAll(r)
+	if fail {
+		return nil, errors.New(fe.name + " is down")
+	} else if err != nil {
+		return nil, err
+	}
+	if fe.delay > 0 {
+		select {
+		case <-time.After(fe.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	id, _ := ids[0].MarshalJSON()
+	return ioutil.NopCloser(strings.NewReader(`{"jsonrpc":"2.0","result":"` + fe.name + `","id":` + string(id) + `}`)), nil
+}
+
+func (fe *fakeEndpoint) SendRequest(context.Context, io.Reader) error { return nil }
+
+func (fe *fakeEndpoint) ReceivedResponse(context.Context) (io.ReadCloser, bool, bool, error) {
+	return nil, false, false, nil
+}
+
+func (fe *fakeEndpoint) Close() error { return nil }
+
+func (fe *fakeEndpoint) setFail(fail bool) {
+	fe.mu.Lock()
+	fe.fail = fail
+	fe.mu.Unlock()
+}
+
+func (fe *fakeEndpoint) callCount() int {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+	return fe.calls
+}
+
+func TestBalancer_RoundRobin(t *testing.T) {
+	a, b, c := &fakeEndpoint{name: "a"}, &fakeEndpoint{name: "b"}, &fakeEndpoint{name: "c"}
+	client := NewClient(NewBalancer([]CallTransport{a, b, c}))
+	defer client.Close()
+
+	var results []string
+	for i := 0; i < 6; i++ {
+		var result string
+		require.NoError(t, client.Do(context.Background(), "name", nil, &result))
+		results = append(results, result)
+	}
+	require.Equal(t, []string{"a", "b", "c", "a", "b", "c"}, results)
+}
+
+func TestBalancer_LeastPending(t *testing.T) {
+	slow, fast := &fakeEndpoint{name: "slow", delay: 200 * time.Millisecond}, &fakeEndpoint{name: "fast"}
+	client := NewClient(NewBalancer([]CallTransport{slow, fast}, WithBalanceStrategy(LeastPending)))
+	defer client.Close()
+
+	call := client.Go(context.Background(), "name", nil) // occupies slow or fast
+	time.Sleep(10 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		var result string
+		require.NoError(t, client.Do(context.Background(), "name", nil, &result))
+		require.Equal(t, "fast", result)
+	}
+	require.NoError(t, call.Err())
+	require.Equal(t, 1, slow.callCount())
+}
+
+func TestBalancer_Failover(t *testing.T) {
+	a, b := &fakeEndpoint{name: "a", fail: true}, &fakeEndpoint{name: "b"}
+	balancer := NewBalancer([]CallTransport{a, b}, WithPassiveEjection(2, 100*time.Millisecond))
+	client := NewClient(balancer)
+	defer client.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 6; i++ {
+		var result string
+		require.NoError(t, client.Do(ctx, "name", nil, &result))
+		require.Equal(t, "b", result)
+	}
+	require.Equal(t, 2, a.callCount()) // ejected after 2 failures
+	require.Equal(t, 1, balancer.Healthy())
+
+	// recovered after ejection duration
+	a.setFail(false)
+	time.Sleep(150 * time.Millisecond)
+	require.Equal(t, 2, balancer.Healthy())
+	results := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		var result string
+		require.NoError(t, client.Do(ctx, "name", nil, &result))
+		results[result] = true
+	}
+	require.Equal(t, map[string]bool{"a": true, "b": true}, results)
+
+	// all endpoints are down
+	a.setFail(true)
+	b.setFail(true)
+	err := client.Do(ctx, "name", nil, nil)
+	require.Error(t, err)
+}
+
+func TestBalancer_Rejected(t *testing.T) {
+	for _, err := range []error{
+		statusError(404),
+		fmt.Errorf("%w: 1", pending.ErrDuplicateID),
+	} {
+		a, b := &fakeEndpoint{name: "a", err: err}, &fakeEndpoint{name: "b", err: err}
+		balancer := NewBalancer([]CallTransport{a, b}, WithPassiveEjection(1, time.Minute))
+		client := NewClient(balancer)
+
+		for i := 0; i < 4; i++ {
+			require.ErrorIs(t, client.Do(context.Background(), "name", nil, nil), err)
+		}
+		require.Equal(t, 2, a.callCount()) // no failover
+		require.Equal(t, 2, b.callCount())
+		require.Equal(t, 2, balancer.Healthy())
+		client.Close()
+	}
+
+	// temporary status error fails over
+	a, b := &fakeEndpoint{name: "a", err: statusError(503)}, &fakeEndpoint{name: "b"}
+	balancer := NewBalancer([]CallTransport{a, b}, WithPassiveEjection(1, time.Minute))
+	client := NewClient(balancer)
+	defer client.Close()
+	for i := 0; i < 4; i++ {
+		var result string
+		require.NoError(t, client.Do(context.Background(), "name", nil, &result))
+		require.Equal(t, "b", result)
+	}
+	require.Equal(t, 1, a.callCount())
+	require.Equal(t, 1, balancer.Healthy())
+}
+
+func TestBalancer_Stream(t *testing.T) {
+	ctx := context.Background()
+	var transports []CallTransport
+	for i := 0; i < 2; i++ {
+		_, l, _ := startServer(t, "tcp", "127.0.0.1:0")
+		st, err := DialTCP(ctx, l.Addr().String())
+		require.NoError(t, err)
+		transports = append(transports, st)
+	}
+	client := NewClient(NewBalancer(transports))
+	defer client.Close()
+
+	// first connection is lost
+	transports[0].Close()
+	for i := 0; i < 4; i++ {
+		var result int
+		require.NoError(t, client.Do(ctx, "sum", []int{i, 1}, &result))
+		require.Equal(t, i+1, result)
+	}
+}
@@ -11,8 +11,8 @@ const defaultBatchCapacity = 5
 type BatchRequest []*Request
 
 // Add appends
-func (reqs BatchRequest) Add(req ...*Request) {
-	reqs = append(reqs, req...)
+func (reqs *BatchRequest) Add(req ...*Request) {
+	*reqs = append(*reqs, req...)
 }
 
 // MarshalJSON implements json.Marshaler
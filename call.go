@@ -0,0 +1,82 @@
+package jrpc
+
+import (
+	"context"
+)
+
+/*
+TODO:
+
+*/
+
+// Call represents an asynchronous call started by Client.Go.
+type Call struct {
+	Method string
+	ID     ID
+
+	done chan struct{}
+	resp *Response
+	err  error
+}
+
+// Go calls method asynchronously, and returns Call immediately.
+// Completion of the call is notified by Call.Done.
+// Calls can be performed in parallel when the transport implements CallTransport, or WithMultiplexing is used.
+func (c *Client) Go(ctx context.Context, method string, params interface{}) *Call {
+	call := &Call{
+		Method: method,
+		done:   make(chan struct{}),
+	}
+	req, err := NewRequest(method, params, c.options.idFactory.CreateID())
+	if err != nil {
+		call.finish(nil, err)
+		return call
+	}
+	call.ID = req.ID
+
+	go func() {
+		call.finish(c.Call(ctx, req))
+	}()
+	return call
+}
+
+func (call *Call) finish(resp *Response, err error) {
+	call.resp = resp
+	call.err = err
+	close(call.done)
+}
+
+// Done returns a channel that's closed when the call is completed.
+func (call *Call) Done() <-chan struct{} {
+	return call.done
+}
+
+// Err waits for completion of the call, and returns error of the transport or error of the response.
+func (call *Call) Err() error {
+	<-call.done
+	if call.err != nil {
+		return call.err
+	} else if call.resp.Error != nil {
+		return call.resp.Error
+	}
+	return nil
+}
+
+// Result waits for completion of the call, and decodes result into v.
+// Same as Err, it returns error of the transport or error of the response.
+func (call *Call) Result(v interface{}) error {
+	err := call.Err()
+	if err != nil {
+		return err
+	} else if v == nil || call.resp.Result == nil {
+		return nil
+	}
+	return call.resp.DecodeResult(v)
+}
+
+// Response waits for completion of the call, and returns the response.
+// It returns nil when the call failed before receiving response.
+func (call *Call) Response() *Response {
+	<-call.done
+	return call.resp
+}
@@ -0,0 +1,45 @@
+package jrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Go(t *testing.T) {
+	_, l, _ := startServer(t, "tcp", "127.0.0.1:0")
+	st, err := DialTCP(context.Background(), l.Addr().String())
+	require.NoError(t, err)
+	client := NewClient(st)
+	defer client.Close()
+	ctx := context.Background()
+
+	calls := make([]*Call, 50)
+	for i := range calls {
+		calls[i] = client.Go(ctx, "sum", []int{i, i})
+	}
+	for i, call := range calls {
+		select {
+		case <-call.Done():
+		case <-time.After(time.Second):
+			t.Fatal("call is not completed")
+		}
+		var result int
+		require.NoError(t, call.Result(&result))
+		require.Equal(t, i*2, result)
+		require.Equal(t, call.ID, call.Response().ID)
+	}
+
+	call := client.Go(ctx, "unknown", nil)
+	err = call.Err()
+	require.IsType(t, &Error{}, err)
+	require.Equal(t, ErrorCodeMethodNotFound, err.(*Error).Code)
+	require.Equal(t, err, call.Result(nil))
+
+	// invalid params
+	call = client.Go(ctx, "sum", func() {})
+	require.Error(t, call.Err())
+	require.Nil(t, call.Response())
+}
@@ -1,9 +1,12 @@
 package jrpc
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"io"
+	"io/ioutil"
+	"sync"
 
 	"github.com/pkg/errors"
 )
@@ -16,7 +19,11 @@ TODO:
 // Client is
 type Client struct {
 	Transport ClientTransport
+	calls     CallTransport
+	batcher   *autoBatcher
+	mu        sync.Mutex // serializes sending and receiving without CallTransport
 	dec       *json.Decoder
+	frames    FrameReader
 	options   clientOptions
 }
 
@@ -29,6 +36,14 @@ func NewClient(t ClientTransport, opts ...ClientOption) *Client {
 	for _, opt := range opts {
 		opt.apply(&client.options)
 	}
+	if ct, ok := t.(CallTransport); ok {
+		client.calls = ct
+	} else if client.options.multiplexing {
+		client.calls = newMuxTransport(t, client.options.framer)
+	}
+	if client.options.batchWindow > 0 {
+		client.batcher = newAutoBatcher(client, client.options.batchWindow, client.options.batchMaxSize)
+	}
 	return client
 }
 
@@ -39,16 +54,43 @@ type ClientTransport interface {
 	Close() error
 }
 
+// CallTransport is ClientTransport that can be used by concurrent callers.
+// RoundTrip sends r and returns the response message for the request that has ids.
+// The returned message is not framed. If ids is empty, RoundTrip returns nil after sending r.
+// Client prefers RoundTrip to SendRequest and ReceivedResponse.
+type CallTransport interface {
+	ClientTransport
+	RoundTrip(ctx context.Context, r io.Reader, ids []ID) (io.ReadCloser, error)
+}
+
 // Call is
 // レスポンスを待つかどうか、ClientTransportの設定次第
 // タイムアウトは、http.Clientのものも使用できるし、contextのDeadlineでも
 func (c *Client) Call(ctx context.Context, req *Request) (*Response, error) {
-	buf, err := req.encodeLine()
+	return c.invoke(ctx, req)
+}
+
+// invoke sends a single request.
+// With WithAutoBatching, the request may be sent together with others.
+func (c *Client) invoke(ctx context.Context, req *Request) (*Response, error) {
+	return c.options.interceptors.chained(ctx, req, c.send)
+}
+
+// send is the end of interceptor chain for a single request.
+func (c *Client) send(ctx context.Context, req *Request) (*Response, error) {
+	if c.batcher != nil && req.ID != NoID {
+		return c.batcher.do(ctx, req)
+	}
+	return c.invokeDirect(ctx, req)
+}
+
+func (c *Client) invokeDirect(ctx context.Context, req *Request) (*Response, error) {
+	buf, err := req.encode()
 	if err != nil {
 		return nil, err
 	}
 	var resp Response
-	err = c.call(ctx, buf, &resp, nil)
+	err = c.call(ctx, buf, requestIDs(req), &resp, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -58,14 +100,28 @@ func (c *Client) Call(ctx context.Context, req *Request) (*Response, error) {
 // CallBatch is
 // レスポンスを待つかどうか、ClientTransportの設定次第
 func (c *Client) CallBatch(ctx context.Context, reqs BatchRequest) (BatchResponse, error) {
-	buf, err := reqs.encodeLine()
+	if len(c.options.interceptors) > 0 {
+		return c.callBatchIntercepted(ctx, reqs)
+	}
+	return c.callBatch(ctx, reqs)
+}
+
+func (c *Client) callBatch(ctx context.Context, reqs BatchRequest) (BatchResponse, error) {
+	if c.options.maxBatchLength > 0 || c.options.maxBatchBytes > 0 {
+		return c.callSplitBatch(ctx, reqs)
+	}
+	return c.sendBatch(ctx, reqs)
+}
+
+func (c *Client) sendBatch(ctx context.Context, reqs BatchRequest) (BatchResponse, error) {
+	buf, err := reqs.encode()
 	if err != nil {
 		return nil, err
 	}
 
 	var resp Response
 	resps := make(BatchResponse, 0, defaultBatchCapacity)
-	err = c.call(ctx, buf, &resp, resps)
+	err = c.call(ctx, buf, requestIDs(reqs...), &resp, &resps)
 	if err != nil {
 		return nil, err
 	}
@@ -82,13 +138,7 @@ func (c *Client) Do(ctx context.Context, method string, params, result interface
 	if err != nil {
 		return err
 	}
-	buf, err := req.encodeLine()
-	if err != nil {
-		return err
-	}
-
-	var resp Response
-	err = c.call(ctx, buf, &resp, nil)
+	resp, err := c.invoke(ctx, req)
 	if err != nil {
 		return err
 	}
@@ -149,7 +199,7 @@ func (c *Client) Batch(ctx context.Context, registerer func(RegisterFunc), unkno
 			// use Client.CallBatch.
 			continue
 		}
-		if target.result != nil {
+		if target.result != nil && resp.Result != nil {
 			err = json.Unmarshal(*resp.Result, target.result)
 			if err != nil {
 				return err
@@ -203,11 +253,16 @@ func (c *Client) Notify(ctx context.Context, method string, params interface{})
 	if err != nil {
 		return err
 	}
-	buf, err := req.encodeLine()
+	_, err = c.options.interceptors.chained(ctx, req, c.notify)
+	return err
+}
+
+func (c *Client) notify(ctx context.Context, req *Request) (*Response, error) {
+	buf, err := req.encode()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return c.call(ctx, buf, nil, nil)
+	return nil, c.call(ctx, buf, nil, nil, nil)
 }
 
 // NotifyWithError is
@@ -217,31 +272,48 @@ func (c *Client) NotifyWithError(ctx context.Context, method string, params inte
 	if err != nil {
 		return err
 	}
-	buf, err := req.encodeLine()
+	resp, err := c.options.interceptors.chained(ctx, req, c.notifyWithError)
 	if err != nil {
 		return err
-	}
-	var resp Response
-	err = c.call(ctx, buf, &resp, nil)
-	if err != nil {
-		return err
-	} else if resp.Error != nil {
+	} else if resp != nil && resp.Error != nil {
 		return resp.Error
 	}
 	// invalid server implementation
 	return nil
 }
 
-func (c *Client) call(ctx context.Context, r io.Reader, resp *Response, batchResp BatchResponse) error {
-	err := c.Transport.SendRequest(ctx, r)
+func (c *Client) notifyWithError(ctx context.Context, req *Request) (*Response, error) {
+	buf, err := req.encode()
 	if err != nil {
-		return nil
+		return nil, err
+	}
+	var resp Response
+	err = c.call(ctx, buf, nil, &resp, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) call(ctx context.Context, buf *Buffer, ids []ID, resp *Response, batchResp *BatchResponse) error {
+	if c.calls != nil {
+		return c.roundTrip(ctx, buf, ids, resp, batchResp)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err := c.Transport.SendRequest(ctx, c.frame(buf))
+	if err != nil {
+		return err
 	}
 
 	responseReader, updated, shouldClose, err := c.Transport.ReceivedResponse(ctx)
 	if err != nil {
 		return err
 	} else if responseReader == nil {
+		if len(ids) > 0 {
+			return ErrMissingResponse
+		}
 		return nil
 	}
 	defer func() {
@@ -252,31 +324,100 @@ func (c *Client) call(ctx context.Context, r io.Reader, resp *Response, batchRes
 
 	if resp == nil && batchResp == nil {
 		return nil
-	} else if updated || (c.dec == nil) {
-		c.dec = json.NewDecoder(responseReader)
 	}
 
-	switch {
-	case batchResp != nil:
-		var raw json.RawMessage
-		err = c.dec.Decode(raw)
+	if c.options.framer != nil {
+		if updated || (c.frames == nil) {
+			c.frames = c.options.framer.NewFrameReader(responseReader)
+		}
+		frame, err := c.frames.ReadFrame()
 		if err != nil {
 			return err
 		}
-		switch raw[0] {
-		case '[':
-			return json.Unmarshal(raw, batchResp)
-		case '{':
-			return json.Unmarshal(raw, resp)
-		default:
-			return errors.New("invalid character found")
+		return unmarshalResponse(frame, resp, batchResp)
+	}
+
+	if updated || (c.dec == nil) {
+		c.dec = json.NewDecoder(responseReader)
+	}
+	if batchResp == nil {
+		return c.dec.Decode(resp)
+	}
+	var raw json.RawMessage
+	err = c.dec.Decode(&raw)
+	if err != nil {
+		return err
+	}
+	return unmarshalResponse(raw, resp, batchResp)
+}
+
+func (c *Client) roundTrip(ctx context.Context, buf *Buffer, ids []ID, resp *Response, batchResp *BatchResponse) error {
+	if resp == nil && batchResp == nil {
+		ids = nil
+	}
+	responseReader, err := c.calls.RoundTrip(ctx, c.frame(buf), ids)
+	if err != nil {
+		return err
+	} else if responseReader == nil {
+		if len(ids) > 0 {
+			return ErrMissingResponse
+		}
+		return nil
+	}
+	defer responseReader.Close()
+
+	data, err := ioutil.ReadAll(responseReader)
+	if err != nil {
+		return err
+	}
+	return unmarshalResponse(data, resp, batchResp)
+}
+
+// requestIDs returns IDs of requests except notifications.
+func requestIDs(reqs ...*Request) []ID {
+	var ids []ID
+	for _, req := range reqs {
+		if req.ID != NoID {
+			ids = append(ids, req.ID)
 		}
+	}
+	return ids
+}
+
+// frame terminates encoded request according to the framing of the Client.
+func (c *Client) frame(buf *Buffer) *Buffer {
+	if c.options.framer == nil {
+		buf.AppendByte('\n')
+		return buf
+	}
+	framed := bufferpool.Get()
+	c.options.framer.NewFrameWriter(framed).WriteFrame(buf.Bytes()) // never fails
+	buf.Free()
+	return framed
+}
+
+func unmarshalResponse(data []byte, resp *Response, batchResp *BatchResponse) error {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	if len(data) == 0 {
+		return io.ErrUnexpectedEOF
+	}
+	switch data[0] {
+	case '[':
+		if batchResp == nil {
+			return errors.New("unexpected batch response")
+		}
+		return json.Unmarshal(data, batchResp)
+	case '{':
+		return json.Unmarshal(data, resp)
 	default:
-		return c.dec.Decode(resp)
+		return errors.New("invalid character found")
 	}
 }
 
 // Close closing connection
 func (c *Client) Close() error {
+	if c.calls != nil {
+		return c.calls.Close()
+	}
 	return c.Transport.Close()
 }
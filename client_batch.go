@@ -0,0 +1,185 @@
+package jrpc
+
+import (
+	"context"
+	"errors"
+)
+
+/*
+TODO:
+
+*/
+
+// ErrNotSent is returned by BatchItem before the batch is sent.
+var ErrNotSent = errors.New("jrpc: batch is not sent yet")
+
+type (
+	// BatchBuilder builds batch request, and gives each call its own handle.
+	//  b := client.NewBatch()
+	//  sum := b.Add("sum", []int{1, 2})
+	//  b.Notify("log", "message")
+	//  err := b.Send(ctx)
+	//  var result int
+	//  err = sum.Result(&result)
+	// AddTyped gives a handle that returns the result as a value of the type.
+	//  sum := jrpc.AddTyped[int](b, "sum", []int{1, 2})
+	//  result, err := sum.Result()
+	BatchBuilder struct {
+		client    *Client
+		idFactory IDFactory
+		reqs      BatchRequest
+		items     []*BatchItem
+		err       error
+		sent      bool
+	}
+
+	// BatchItem is a handle of a call in the batch.
+	BatchItem struct {
+		Method string
+		ID     ID
+		resp   *Response
+		err    error
+	}
+
+	// TypedBatchItem is a handle of a call in the batch, whose result is decoded as R.
+	TypedBatchItem[R any] struct {
+		*BatchItem
+	}
+)
+
+// NewBatch is
+func (c *Client) NewBatch() *BatchBuilder {
+	return &BatchBuilder{
+		client:    c,
+		idFactory: c.options.idFactory.BatchIDFactory(),
+	}
+}
+
+// Add adds a call to the batch.
+// Error of params is reported by Send.
+func (b *BatchBuilder) Add(method string, params interface{}) *BatchItem {
+	item := &BatchItem{
+		Method: method,
+		ID:     b.idFactory.CreateID(),
+		err:    ErrNotSent,
+	}
+	b.add(method, params, item.ID)
+	b.items = append(b.items, item)
+	return item
+}
+
+// AddTyped adds a call to b, and returns the handle that decodes its result as R.
+// Error of params is reported by Send.
+func AddTyped[R any](b *BatchBuilder, method string, params interface{}) *TypedBatchItem[R] {
+	return &TypedBatchItem[R]{
+		BatchItem: b.Add(method, params),
+	}
+}
+
+// Notify adds a notification to the batch. It has no handle because no response is returned.
+// Error of params is reported by Send.
+func (b *BatchBuilder) Notify(method string, params interface{}) {
+	b.add(method, params, NoID)
+}
+
+func (b *BatchBuilder) add(method string, params interface{}, id ID) {
+	req, err := NewRequest(method, params, id)
+	if err != nil {
+		if b.err == nil {
+			b.err = err
+		}
+		return
+	}
+	b.reqs.Add(req)
+}
+
+// Len returns the number of calls and notifications in the batch.
+func (b *BatchBuilder) Len() int {
+	return len(b.reqs)
+}
+
+// Send sends the batch, and sets the response to each BatchItem.
+// It returns error when the batch itself failed, such as transport error or Parse error.
+// Error of each call is returned by BatchItem.
+func (b *BatchBuilder) Send(ctx context.Context) error {
+	if b.err != nil {
+		return b.err
+	} else if b.sent {
+		return errors.New("jrpc: batch is already sent")
+	} else if len(b.reqs) == 0 {
+		return errors.New("no request")
+	}
+	b.sent = true
+
+	resps, err := b.client.CallBatch(ctx, b.reqs)
+	if err != nil {
+		for _, item := range b.items {
+			item.err = err
+		}
+		return err
+	}
+
+	byID := make(map[ID]*Response, len(resps))
+	var unknown *Error
+	for _, resp := range resps {
+		if resp.ID == UnknownID || resp.ID == NoID {
+			if resp.Error != nil && unknown == nil {
+				unknown = resp.Error
+			}
+			continue
+		}
+		byID[resp.ID] = resp
+	}
+	for _, item := range b.items {
+		item.resp = byID[item.ID]
+		if item.resp == nil {
+			item.err = ErrMissingResponse
+		} else {
+			item.err = nil
+		}
+	}
+	if unknown != nil {
+		return unknown
+	}
+	return nil
+}
+
+// Result decodes result of the call into v.
+// It returns JSON-RPC error of the response, ErrMissingResponse if the response is not returned,
+// or ErrNotSent before the batch is sent.
+func (item *BatchItem) Result(v interface{}) error {
+	if item.err != nil {
+		return item.err
+	} else if item.resp.Error != nil {
+		return item.resp.Error
+	} else if v == nil || item.resp.Result == nil {
+		return nil
+	}
+	return item.resp.DecodeResult(v)
+}
+
+// Error returns JSON-RPC error of the response.
+func (item *BatchItem) Error() *Error {
+	if item.resp == nil {
+		return nil
+	}
+	return item.resp.Error
+}
+
+// Missing reports whether the batch was sent but its response was not returned.
+func (item *BatchItem) Missing() bool {
+	return item.err == ErrMissingResponse
+}
+
+// Response returns the response of the call. It returns nil if the response is not returned.
+func (item *BatchItem) Response() *Response {
+	return item.resp
+}
+
+// Result returns result of the call decoded as R.
+// The error is the same as that of BatchItem.Result.
+func (item *TypedBatchItem[R]) Result() (R, error) {
+	var result R
+	err := item.BatchItem.Result(&result)
+	return result, err
+}
@@ -0,0 +1,127 @@
+package jrpc
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchBuilder(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+	sizes := recordingServer(serverConn)
+	client := NewClient(NewStreamTransport(clientConn))
+	defer client.Close()
+	ctx := context.Background()
+
+	b := client.NewBatch()
+	sum := b.Add("sum", []int{1, 2})
+	subtract := b.Add("subtract", []int{1, 2, 3})
+	unknown := b.Add("unknown", nil)
+	b.Notify("sum", []int{4})
+	require.Equal(t, 4, b.Len())
+
+	var result int
+	require.Equal(t, ErrNotSent, sum.Result(&result))
+
+	require.NoError(t, b.Send(ctx))
+	require.Equal(t, 4, <-sizes)
+
+	require.NoError(t, sum.Result(&result))
+	require.Equal(t, 3, result)
+	require.Equal(t, "only two arguments", subtract.Result(&result).(*Error).Message)
+	require.Equal(t, ErrorCodeMethodNotFound, unknown.Error().Code)
+	require.False(t, unknown.Missing())
+
+	require.Error(t, b.Send(ctx)) // already sent
+}
+
+func TestAddTyped(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+	recordingServer(serverConn)
+	client := NewClient(NewStreamTransport(clientConn))
+	defer client.Close()
+
+	b := client.NewBatch()
+	sum := AddTyped[int](b, "sum", []int{1, 2})
+	subtract := AddTyped[int](b, "subtract", []int{1, 2, 3})
+
+	_, err := sum.Result()
+	require.Equal(t, ErrNotSent, err)
+
+	require.NoError(t, b.Send(context.Background()))
+	result, err := sum.Result()
+	require.NoError(t, err)
+	require.Equal(t, 3, result)
+	_, err = subtract.Result()
+	require.Equal(t, "only two arguments", err.(*Error).Message)
+	require.Equal(t, "only two arguments", subtract.Error().Message)
+}
+
+func TestBatchBuilder_Missing(t *testing.T) {
+	// server that ignores the second request
+	transport := &transport{
+		send: func(ctx context.Context, r io.Reader) error {
+			_, err := ioutil.ReadAll(r)
+			return err
+		},
+		recv: func(ctx context.Context) (io.ReadCloser, bool, bool, error) {
+			return ioutil.NopCloser(strings.NewReader(`[{"jsonrpc":"2.0","id":1}]`)), true, true, nil
+		},
+	}
+	client := NewClient(transport, WithIDFactory(&sequentialIDFactory{}))
+
+	b := client.NewBatch()
+	first := b.Add("first", nil)
+	second := b.Add("second", nil)
+	require.NoError(t, b.Send(context.Background()))
+
+	var result string
+	require.NoError(t, first.Result(&result)) // result is omitted
+	require.Equal(t, "", result)
+	require.Nil(t, first.Error())
+	require.True(t, second.Missing())
+	require.Equal(t, ErrMissingResponse, second.Result(&result))
+
+	// error for the whole batch
+	transport.recv = func(ctx context.Context) (io.ReadCloser, bool, bool, error) {
+		return ioutil.NopCloser(strings.NewReader(`{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`)), true, true, nil
+	}
+	b = client.NewBatch()
+	item := b.Add("first", nil)
+	err := b.Send(context.Background())
+	require.Equal(t, ErrorCodeParse, err.(*Error).Code)
+	require.True(t, item.Missing())
+
+	// invalid params
+	b = client.NewBatch()
+	b.Add("first", func() {})
+	require.Error(t, b.Send(context.Background()))
+}
+
+func TestClient_Batch(t *testing.T) {
+	_, l, _ := startServer(t, "tcp", "127.0.0.1:0")
+	st, err := DialTCP(context.Background(), l.Addr().String())
+	require.NoError(t, err)
+	client := NewClient(st)
+	defer client.Close()
+
+	var sum int
+	var sumErr, subtractErr *Error
+	var subtract int
+	err = client.Batch(context.Background(), func(register RegisterFunc) {
+		register("sum", []int{1, 2}, &sum, &sumErr)
+		register("subtract", []int{1}, &subtract, &subtractErr)
+		register("sum", []int{3}, nil, nil)
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 3, sum)
+	require.Nil(t, sumErr)
+	require.Equal(t, "only two arguments", subtractErr.Message)
+}
@@ -0,0 +1,171 @@
+package jrpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+/*
+TODO:
+
+*/
+
+// ErrMissingResponse is returned when the response for the request is not returned,
+// such as when the batch response does not contain it, or the server returns no response to a call.
+var ErrMissingResponse = errors.New("jrpc: response is missing")
+
+// WithAutoBatching makes Client collect calls made within window, and send them as a batch.
+// The batch is sent immediately when the number of calls reaches maxSize. If maxSize <= 0, it is unlimited.
+// Only requests with ID (Call, Do and Go) are collected. Notifications and CallBatch are sent as is.
+func WithAutoBatching(window time.Duration, maxSize int) ClientOption {
+	return clientOptionFunc(func(opts *clientOptions) {
+		opts.batchWindow = window
+		opts.batchMaxSize = maxSize
+	})
+}
+
+type (
+	autoBatcher struct {
+		client  *Client
+		window  time.Duration
+		maxSize int
+
+		mu      sync.Mutex
+		entries []*batchEntry
+		timer   *time.Timer
+	}
+
+	batchEntry struct {
+		ctx  context.Context
+		req  *Request
+		done chan struct{}
+		resp *Response
+		err  error
+	}
+)
+
+func newAutoBatcher(c *Client, window time.Duration, maxSize int) *autoBatcher {
+	return &autoBatcher{
+		client:  c,
+		window:  window,
+		maxSize: maxSize,
+	}
+}
+
+func (b *autoBatcher) do(ctx context.Context, req *Request) (*Response, error) {
+	e := &batchEntry{
+		ctx:  ctx,
+		req:  req,
+		done: make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	b.entries = append(b.entries, e)
+	if len(b.entries) == 1 {
+		b.timer = time.AfterFunc(b.window, b.flush)
+	}
+	if b.maxSize > 0 && len(b.entries) >= b.maxSize {
+		b.timer.Stop()
+		go b.send(b.take())
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-e.done:
+		return e.resp, e.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (b *autoBatcher) flush() {
+	b.mu.Lock()
+	entries := b.take()
+	b.mu.Unlock()
+	b.send(entries)
+}
+
+// take returns collected entries. b.mu must be held.
+func (b *autoBatcher) take() []*batchEntry {
+	entries := b.entries
+	b.entries = nil
+	return entries
+}
+
+func (b *autoBatcher) send(entries []*batchEntry) {
+	// calls cancelled while waiting are not sent
+	live := entries[:0]
+	for _, e := range entries {
+		if e.ctx.Err() == nil {
+			live = append(live, e)
+		}
+	}
+	switch len(live) {
+	case 0:
+		return
+	case 1:
+		e := live[0]
+		e.resp, e.err = b.client.invokeDirect(e.ctx, e.req)
+		close(e.done)
+		return
+	}
+
+	ctx, cancel := batchContext(live)
+	defer cancel()
+	reqs := make(BatchRequest, len(live))
+	for i, e := range live {
+		reqs[i] = e.req
+	}
+	resps, err := b.client.callBatch(ctx, reqs)
+	dispatch(live, resps, err)
+}
+
+// dispatch passes each response in resps to the entry that has the same ID, and finishes entries.
+// A response without valid ID that has error (such as Parse error) is passed to the entries that miss response.
+// Entries of notification get no response.
+func dispatch(entries []*batchEntry, resps BatchResponse, err error) (unknown *Response) {
+	var byID map[ID]*Response
+	if err == nil {
+		byID = make(map[ID]*Response, len(resps))
+		for _, resp := range resps {
+			if resp.ID == UnknownID || resp.ID == NoID {
+				unknown = resp
+			} else {
+				byID[resp.ID] = resp
+			}
+		}
+	}
+	for _, e := range entries {
+		switch {
+		case err != nil:
+			e.err = err
+		case e.req.ID == NoID:
+		case byID[e.req.ID] != nil:
+			e.resp = byID[e.req.ID]
+		case unknown != nil && unknown.Error != nil:
+			e.resp = unknown
+		default:
+			e.err = ErrMissingResponse
+		}
+		close(e.done)
+	}
+	return unknown
+}
+
+// batchContext returns context for the batch.
+// The batch is given the latest deadline of the calls, only if all of them have deadline.
+func batchContext(entries []*batchEntry) (context.Context, context.CancelFunc) {
+	var latest time.Time
+	for _, e := range entries {
+		deadline, ok := e.ctx.Deadline()
+		if !ok {
+			return context.WithCancel(context.Background())
+		}
+		if deadline.After(latest) {
+			latest = deadline
+		}
+	}
+	return context.WithDeadline(context.Background(), latest)
+}
@@ -0,0 +1,114 @@
+package jrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordingServer serves core over conn, and records whether each received message is batch.
+func recordingServer(conn net.Conn) <-chan int {
+	sizes := make(chan int, 100)
+	go func() {
+		defer close(sizes)
+		core := newMock()
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadBytes('\n')
+			if err != nil {
+				return
+			}
+			var batch []json.RawMessage
+			if json.Unmarshal(line, &batch) == nil {
+				sizes <- len(batch)
+			} else {
+				sizes <- 0 // single
+			}
+			dec := NewDecoder(bytes.NewReader(line))
+			reqs, isBatch, _ := dec.Decode(nil)
+			resps, _ := core.Execute(context.Background(), reqs, isBatch)
+			if len(resps) > 0 {
+				NewEncoder(conn).Encode(resps, isBatch)
+			}
+		}
+	}()
+	return sizes
+}
+
+func TestClient_AutoBatching(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+	sizes := recordingServer(serverConn)
+	client := NewClient(NewStreamTransport(clientConn), WithAutoBatching(50*time.Millisecond, 3))
+	defer client.Close()
+	ctx := context.Background()
+
+	do := func(n int) {
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				var result int
+				require.NoError(t, client.Do(ctx, "sum", []int{i, 1}, &result))
+				require.Equal(t, i+1, result)
+			}(i)
+		}
+		wg.Wait()
+	}
+
+	// window
+	do(2)
+	require.Equal(t, 2, <-sizes)
+
+	// max size
+	start := time.Now()
+	do(3)
+	require.Equal(t, 3, <-sizes)
+	require.Less(t, int64(time.Since(start)), int64(50*time.Millisecond))
+
+	// single call is not wrapped by batch
+	do(1)
+	require.Equal(t, 0, <-sizes)
+
+	// JSON-RPC error is passed to each caller
+	calls := []*Call{
+		client.Go(ctx, "unknown", nil),
+		client.Go(ctx, "sum", []int{1, 2}),
+	}
+	require.Equal(t, 2, <-sizes)
+	require.IsType(t, &Error{}, calls[0].Err())
+	var result int
+	require.NoError(t, calls[1].Result(&result))
+	require.Equal(t, 3, result)
+
+	// notification is not batched
+	require.NoError(t, client.Notify(ctx, "sum", []int{1}))
+	require.Equal(t, 0, <-sizes)
+}
+
+func TestClient_AutoBatchingCancel(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+	sizes := recordingServer(serverConn)
+	client := NewClient(NewStreamTransport(clientConn), WithAutoBatching(50*time.Millisecond, 0))
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := client.Go(ctx, "sum", []int{1})
+	call := client.Go(context.Background(), "sum", []int{2})
+	cancel()
+
+	require.Equal(t, context.Canceled, cancelled.Err())
+	var result int
+	require.NoError(t, call.Result(&result))
+	require.Equal(t, 2, result)
+	require.Equal(t, 0, <-sizes) // cancelled call is not sent
+}
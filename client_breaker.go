@@ -0,0 +1,242 @@
+package jrpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+/*
+TODO:
+
+*/
+
+// CircuitState is state of circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed passes calls.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails calls fast.
+	CircuitOpen
+	// CircuitHalfOpen passes a single probe call.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitOpenError is returned without sending request while the circuit is open.
+type CircuitOpenError struct {
+	Method string // empty if the circuit is shared by all methods
+}
+
+func (e *CircuitOpenError) Error() string {
+	if e.Method == "" {
+		return "jrpc: circuit breaker is open"
+	}
+	return "jrpc: circuit breaker is open for method " + e.Method
+}
+
+// CircuitBreaker is
+// Zero value of each field is replaced with the value of DefaultCircuitBreaker.
+// OnStateChange is called without holding the state of the breaker, so it may call through the same Client.
+// It may be called concurrently.
+type CircuitBreaker struct {
+	Window        time.Duration // period of counting calls
+	MinCalls      int           // the circuit is never opened until the number of calls in Window reaches MinCalls
+	FailureRate   float64       // the circuit is opened when the rate of failures in Window reaches FailureRate
+	SlowCall      time.Duration // call slower than SlowCall is counted as failure. 0 disables
+	OpenDuration  time.Duration // duration until half-open probe
+	PerMethod     bool          // if true, each method has its own circuit
+	IsFailure     func(err error, resp *Response) bool
+	OnStateChange func(method string, from, to CircuitState) // method is empty unless PerMethod
+}
+
+// DefaultCircuitBreaker is
+var DefaultCircuitBreaker = CircuitBreaker{
+	Window:       10 * time.Second,
+	MinCalls:     10,
+	FailureRate:  0.5,
+	OpenDuration: 30 * time.Second,
+	IsFailure:    DefaultIsFailure,
+}
+
+// DefaultIsFailure counts transport errors except cancellation of context,
+// Internal error and JSON-RPC errors of the server-error range as failure.
+func DefaultIsFailure(err error, resp *Response) bool {
+	if err != nil {
+		var jrpcErr *Error
+		if errors.As(err, &jrpcErr) {
+			return false // rejected by interceptor
+		}
+		return !errors.Is(err, context.Canceled)
+	}
+	if resp == nil || resp.Error == nil {
+		return false
+	}
+	code := resp.Error.Code
+	return code == ErrorCodeInternal || (code >= ErrorCodeServerErrorMin && code <= ErrorCodeServerErrorMax)
+}
+
+// WithCircuitBreaker installs cb as ClientInterceptor.
+func WithCircuitBreaker(cb CircuitBreaker) ClientOption {
+	return WithClientInterceptors(cb.Interceptor())
+}
+
+type circuit struct {
+	state       CircuitState
+	windowStart time.Time
+	calls       int
+	failures    int
+	openedAt    time.Time
+	probing     bool
+}
+
+// stateChange is a transition of circuit, passed to OnStateChange after the lock is released.
+type stateChange struct {
+	key      string
+	from, to CircuitState
+}
+
+// Interceptor returns ClientInterceptor that applies the circuit breaker.
+func (cb CircuitBreaker) Interceptor() ClientInterceptor {
+	cb = cb.withDefaults()
+	var mu sync.Mutex
+	circuits := make(map[string]*circuit)
+
+	return func(ctx context.Context, req *Request, invoker Invoker) (*Response, error) {
+		var key string
+		if cb.PerMethod {
+			key = req.Method
+		}
+
+		mu.Lock()
+		c, ok := circuits[key]
+		if !ok {
+			c = &circuit{
+				windowStart: time.Now(),
+			}
+			circuits[key] = c
+		}
+		probe, allowed, change := cb.allow(key, c)
+		mu.Unlock()
+		cb.notify(change)
+		if !allowed {
+			return nil, &CircuitOpenError{
+				Method: key,
+			}
+		}
+
+		start := time.Now()
+		resp, err := invoker(ctx, req)
+		failed := cb.IsFailure(err, resp) || (cb.SlowCall > 0 && time.Since(start) > cb.SlowCall)
+
+		mu.Lock()
+		if probe && ctx.Err() != nil {
+			// probe is cancelled or timed out by the caller, so the result tells nothing
+			c.probing = false
+			change = stateChange{}
+		} else {
+			change = cb.record(key, c, probe, failed)
+		}
+		mu.Unlock()
+		cb.notify(change)
+		return resp, err
+	}
+}
+
+func (cb CircuitBreaker) withDefaults() CircuitBreaker {
+	d := DefaultCircuitBreaker
+	if cb.Window == 0 {
+		cb.Window = d.Window
+	}
+	if cb.MinCalls == 0 {
+		cb.MinCalls = d.MinCalls
+	}
+	if cb.FailureRate == 0 {
+		cb.FailureRate = d.FailureRate
+	}
+	if cb.OpenDuration == 0 {
+		cb.OpenDuration = d.OpenDuration
+	}
+	if cb.IsFailure == nil {
+		cb.IsFailure = d.IsFailure
+	}
+	return cb
+}
+
+// allow reports whether the call can be sent, and whether the call is half-open probe.
+func (cb CircuitBreaker) allow(key string, c *circuit) (probe, allowed bool, change stateChange) {
+	switch c.state {
+	case CircuitOpen:
+		if time.Since(c.openedAt) < cb.OpenDuration {
+			return false, false, change
+		}
+		change = cb.transition(key, c, CircuitHalfOpen)
+		fallthrough
+	case CircuitHalfOpen:
+		if c.probing {
+			return false, false, change
+		}
+		c.probing = true
+		return true, true, change
+	default:
+		return false, true, change
+	}
+}
+
+func (cb CircuitBreaker) record(key string, c *circuit, probe, failed bool) stateChange {
+	if probe {
+		c.probing = false
+		if failed {
+			return cb.open(key, c)
+		}
+		c.windowStart, c.calls, c.failures = time.Now(), 0, 0
+		return cb.transition(key, c, CircuitClosed)
+	} else if c.state != CircuitClosed {
+		return stateChange{} // result of the call sent before opened
+	}
+
+	if time.Since(c.windowStart) > cb.Window {
+		c.windowStart, c.calls, c.failures = time.Now(), 0, 0
+	}
+	c.calls++
+	if failed {
+		c.failures++
+	}
+	if c.calls >= cb.MinCalls && float64(c.failures) >= cb.FailureRate*float64(c.calls) {
+		return cb.open(key, c)
+	}
+	return stateChange{}
+}
+
+func (cb CircuitBreaker) open(key string, c *circuit) stateChange {
+	c.openedAt = time.Now()
+	return cb.transition(key, c, CircuitOpen)
+}
+
+// transition changes the state of c, and returns the change to be notified.
+func (cb CircuitBreaker) transition(key string, c *circuit, to CircuitState) stateChange {
+	from := c.state
+	c.state = to
+	return stateChange{key: key, from: from, to: to}
+}
+
+// notify calls OnStateChange with change. It must be called without lock.
+func (cb CircuitBreaker) notify(change stateChange) {
+	if cb.OnStateChange != nil && change.from != change.to {
+		cb.OnStateChange(change.key, change.from, change.to)
+	}
+}
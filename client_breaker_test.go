@@ -0,0 +1,165 @@
+package jrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	type change struct {
+		method   string
+		from, to CircuitState
+	}
+	var changes []change
+	intercept := CircuitBreaker{
+		MinCalls:     4,
+		FailureRate:  0.5,
+		OpenDuration: 50 * time.Millisecond,
+		PerMethod:    true,
+		OnStateChange: func(method string, from, to CircuitState) {
+			changes = append(changes, change{method, from, to})
+		},
+	}.Interceptor()
+
+	var fail bool
+	var sent int
+	invoker := func(ctx context.Context, req *Request) (*Response, error) {
+		sent++
+		if fail {
+			return nil, errors.New("connection refused")
+		}
+		return &Response{Version: "2.0", ID: req.ID}, nil
+	}
+	call := func(method string) error {
+		req, _ := NewRequest(method, nil, NewID(1))
+		_, err := intercept(context.Background(), req, invoker)
+		return err
+	}
+
+	// 2 failures in 4 calls
+	require.NoError(t, call("a"))
+	require.NoError(t, call("a"))
+	fail = true
+	require.Error(t, call("a"))
+	require.Error(t, call("a"))
+	require.Equal(t, []change{{"a", CircuitClosed, CircuitOpen}}, changes)
+
+	// fail fast
+	sent = 0
+	err := call("a")
+	var openErr *CircuitOpenError
+	require.True(t, errors.As(err, &openErr))
+	require.Equal(t, "a", openErr.Method)
+	require.Equal(t, 0, sent)
+
+	// other method has its own circuit
+	require.Error(t, call("b"))
+	require.Equal(t, 1, sent)
+
+	// failed probe opens again
+	time.Sleep(60 * time.Millisecond)
+	require.Error(t, call("a"))
+	require.IsType(t, &CircuitOpenError{}, call("a"))
+	require.Equal(t, []change{
+		{"a", CircuitClosed, CircuitOpen},
+		{"a", CircuitOpen, CircuitHalfOpen},
+		{"a", CircuitHalfOpen, CircuitOpen},
+	}, changes[:3])
+
+	// succeeded probe closes
+	time.Sleep(60 * time.Millisecond)
+	fail = false
+	require.NoError(t, call("a"))
+	require.NoError(t, call("a"))
+	require.Equal(t, change{"a", CircuitHalfOpen, CircuitClosed}, changes[len(changes)-1])
+}
+
+func TestCircuitBreaker_OnStateChange(t *testing.T) {
+	var intercept ClientInterceptor
+	reentered := make(chan error, 1)
+	intercept = CircuitBreaker{
+		MinCalls:     1,
+		OpenDuration: time.Minute,
+		OnStateChange: func(method string, from, to CircuitState) {
+			// calling through the same breaker must not deadlock
+			req, _ := NewRequest("m", nil, NewID(2))
+			_, err := intercept(context.Background(), req, nil)
+			reentered <- err
+		},
+	}.Interceptor()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req, _ := NewRequest("m", nil, NewID(1))
+		intercept(context.Background(), req, func(ctx context.Context, req *Request) (*Response, error) {
+			return nil, errors.New("connection refused")
+		})
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("deadlock in OnStateChange")
+	}
+	require.IsType(t, &CircuitOpenError{}, <-reentered)
+}
+
+func TestCircuitBreaker_CancelledProbe(t *testing.T) {
+	var changes []CircuitState
+	intercept := CircuitBreaker{
+		MinCalls:     1,
+		OpenDuration: 10 * time.Millisecond,
+		OnStateChange: func(method string, from, to CircuitState) {
+			changes = append(changes, to)
+		},
+	}.Interceptor()
+	call := func(ctx context.Context, invoker Invoker) error {
+		req, _ := NewRequest("m", nil, NewID(1))
+		_, err := intercept(ctx, req, invoker)
+		return err
+	}
+	fail := func(ctx context.Context, req *Request) (*Response, error) {
+		return nil, errors.New("connection refused")
+	}
+	cancelled := func(ctx context.Context, req *Request) (*Response, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	succeed := func(ctx context.Context, req *Request) (*Response, error) {
+		return &Response{Version: "2.0", ID: req.ID}, nil
+	}
+
+	require.Error(t, call(context.Background(), fail))
+	time.Sleep(20 * time.Millisecond)
+
+	// cancelled probe leaves the circuit half-open
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(t, context.Canceled, call(ctx, cancelled))
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, call(ctx, cancelled))
+	require.Equal(t, []CircuitState{CircuitOpen, CircuitHalfOpen}, changes)
+
+	// and the next call probes again
+	require.NoError(t, call(context.Background(), succeed))
+	require.Equal(t, []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitClosed}, changes)
+}
+
+func TestCircuitBreaker_SlowCall(t *testing.T) {
+	client := NewClient(&fakeEndpoint{name: "slow", delay: 20 * time.Millisecond}, WithCircuitBreaker(CircuitBreaker{
+		MinCalls:     2,
+		SlowCall:     10 * time.Millisecond,
+		OpenDuration: time.Minute,
+	}))
+	ctx := context.Background()
+
+	require.NoError(t, client.Do(ctx, "name", nil, nil))
+	require.NoError(t, client.Do(ctx, "name", nil, nil))
+	err := client.Do(ctx, "name", nil, nil)
+	require.Equal(t, &CircuitOpenError{}, err)
+}
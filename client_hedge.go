@@ -0,0 +1,163 @@
+package jrpc
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+TODO:
+
+*/
+
+// HedgePolicy is
+type HedgePolicy struct {
+	Delay     time.Duration // a hedged request is sent when no response has arrived within Delay
+	MaxHedges int           // maximum number of hedged requests per call. Default is 1
+
+	// Safe reports whether req can be hedged. IdempotentMethods can be used.
+	// Default is nil, which means that no request is hedged.
+	Safe func(req *Request) bool
+
+	// IDFactory creates IDs of hedged requests. Default is nil, which derives string ID from the original ID,
+	// such as "1.hedge1". Set the IDFactory of Client for the server that accepts only numeric IDs.
+	IDFactory IDFactory
+}
+
+// HedgeStats is
+type HedgeStats struct {
+	Calls  uint64 // calls that are eligible for hedging
+	Hedged uint64 // calls that sent hedged request at least once
+	Wins   uint64 // calls that got response of hedged request first
+}
+
+// Hedger sends duplicate requests for latency-sensitive calls.
+// The first response wins, and the others are cancelled.
+// Hedged requests have their own IDs (see HedgePolicy.IDFactory),
+// and the winning response is returned with the original ID.
+// With Balancer, hedged requests are sent to the endpoints that are not used by the others.
+type Hedger struct {
+	policy HedgePolicy
+	calls  uint64
+	hedged uint64
+	wins   uint64
+}
+
+// NewHedger is
+func NewHedger(policy HedgePolicy) *Hedger {
+	if policy.MaxHedges <= 0 {
+		policy.MaxHedges = 1
+	}
+	return &Hedger{
+		policy: policy,
+	}
+}
+
+// WithHedging installs h as ClientInterceptor.
+func WithHedging(h *Hedger) ClientOption {
+	return WithClientInterceptors(h.Interceptor())
+}
+
+// Stats returns how often hedged requests were used.
+func (h *Hedger) Stats() HedgeStats {
+	return HedgeStats{
+		Calls:  atomic.LoadUint64(&h.calls),
+		Hedged: atomic.LoadUint64(&h.hedged),
+		Wins:   atomic.LoadUint64(&h.wins),
+	}
+}
+
+type hedgeResult struct {
+	resp *Response
+	err  error
+	n    int // 0 for the original request
+}
+
+// Interceptor returns ClientInterceptor that hedges safe requests.
+func (h *Hedger) Interceptor() ClientInterceptor {
+	return func(ctx context.Context, req *Request, invoker Invoker) (*Response, error) {
+		if req.ID == NoID || h.policy.Safe == nil || !h.policy.Safe(req) {
+			return invoker(ctx, req)
+		}
+		atomic.AddUint64(&h.calls, 1)
+
+		parent := ctx
+		ctx, cancel := context.WithCancel(context.WithValue(ctx, hedgeGroupKey{}, &hedgeGroup{}))
+		defer cancel() // cancel the others
+
+		results := make(chan hedgeResult, 1+h.policy.MaxHedges)
+		launch := func(r *Request, n int) {
+			go func() {
+				resp, err := invoker(ctx, r)
+				results <- hedgeResult{resp, err, n}
+			}()
+		}
+		launch(req, 0)
+
+		timer := time.NewTimer(h.policy.Delay)
+		defer timer.Stop()
+		inflight, hedges := 1, 0
+		for {
+			select {
+			case r := <-results:
+				inflight--
+				if r.err != nil && inflight > 0 {
+					continue // wait for the others
+				}
+				if r.n > 0 && r.err == nil {
+					atomic.AddUint64(&h.wins, 1)
+					if r.resp != nil {
+						resp := *r.resp
+						resp.ID = req.ID
+						r.resp = &resp
+					}
+				}
+				return r.resp, r.err
+			case <-timer.C:
+				if hedges == h.policy.MaxHedges {
+					continue
+				}
+				if hedges == 0 {
+					atomic.AddUint64(&h.hedged, 1)
+				}
+				hedges++
+				hedged := *req
+				if h.policy.IDFactory != nil {
+					hedged.ID = h.policy.IDFactory.CreateID()
+				} else {
+					hedged.ID = NewID(req.ID.String() + ".hedge" + strconv.Itoa(hedges))
+				}
+				launch(&hedged, hedges)
+				inflight++
+				timer.Reset(h.policy.Delay)
+			case <-parent.Done():
+				return nil, parent.Err()
+			}
+		}
+	}
+}
+
+type hedgeGroupKey struct{}
+
+// hedgeGroup records endpoints of Balancer used by the original and hedged requests.
+type hedgeGroup struct {
+	mu   sync.Mutex
+	used []*endpoint
+}
+
+func (g *hedgeGroup) use(ep *endpoint) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.used = append(g.used, ep)
+}
+
+func (g *hedgeGroup) exclude(tried map[*endpoint]bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, ep := range g.used {
+		tried[ep] = true
+	}
+}
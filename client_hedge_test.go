@@ -0,0 +1,102 @@
+package jrpc
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHedger(t *testing.T) {
+	slow := &fakeEndpoint{name: "slow", delay: 200 * time.Millisecond}
+	fast := &fakeEndpoint{name: "fast", delay: 10 * time.Millisecond}
+	hedger := NewHedger(HedgePolicy{
+		Delay: 30 * time.Millisecond,
+		Safe:  IdempotentMethods("get"),
+	})
+	client := NewClient(NewBalancer([]CallTransport{slow, fast}), WithHedging(hedger), WithIDFactory(&sequentialIDFactory{}))
+	defer client.Close()
+	ctx := context.Background()
+
+	// original goes to slow, hedged request goes to fast
+	start := time.Now()
+	resp, err := client.Call(ctx, &Request{Version: "2.0", Method: "get", ID: NewID(1)})
+	require.NoError(t, err)
+	require.Less(t, int64(time.Since(start)), int64(150*time.Millisecond))
+	require.Equal(t, `"fast"`, string(*resp.Result))
+	require.Equal(t, NewID(1), resp.ID)
+	require.Equal(t, HedgeStats{Calls: 1, Hedged: 1, Wins: 1}, hedger.Stats())
+
+	// not safe
+	var result string
+	for i := 0; i < 2; i++ {
+		require.NoError(t, client.Do(ctx, "post", nil, &result))
+	}
+	require.Equal(t, 2, slow.callCount())
+	require.Equal(t, 2, fast.callCount())
+	require.Equal(t, uint64(1), hedger.Stats().Calls)
+
+	// response arrives before delay
+	client = NewClient(fast, WithHedging(hedger))
+	require.NoError(t, client.Do(ctx, "get", nil, &result))
+	require.Equal(t, "fast", result)
+	require.Equal(t, HedgeStats{Calls: 2, Hedged: 1, Wins: 1}, hedger.Stats())
+}
+
+func TestHedger_Cancel(t *testing.T) {
+	slow := &fakeEndpoint{name: "slow", delay: time.Second}
+	hedger := NewHedger(HedgePolicy{
+		Delay:     10 * time.Millisecond,
+		MaxHedges: 2,
+		Safe:      func(*Request) bool { return true },
+	})
+	client := NewClient(slow, WithHedging(hedger))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := client.Do(ctx, "get", nil, nil)
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.Equal(t, 3, slow.callCount())
+	require.Equal(t, HedgeStats{Calls: 1, Hedged: 1}, hedger.Stats())
+}
+
+// idRecorder records IDs of requests sent through CallTransport.
+type idRecorder struct {
+	CallTransport
+	mu  sync.Mutex
+	ids []ID
+}
+
+func (r *idRecorder) RoundTrip(ctx context.Context, body io.Reader, ids []ID) (io.ReadCloser, error) {
+	r.mu.Lock()
+	r.ids = append(r.ids, ids...)
+	r.mu.Unlock()
+	return r.CallTransport.RoundTrip(ctx, body, ids)
+}
+
+func TestHedger_IDFactory(t *testing.T) {
+	for _, factory := range []IDFactory{nil, &sequentialIDFactory{n: 100}} {
+		slow := &idRecorder{CallTransport: &fakeEndpoint{name: "slow", delay: 200 * time.Millisecond}}
+		fast := &idRecorder{CallTransport: &fakeEndpoint{name: "fast", delay: 10 * time.Millisecond}}
+		hedger := NewHedger(HedgePolicy{
+			Delay:     30 * time.Millisecond,
+			Safe:      IdempotentMethods("get"),
+			IDFactory: factory,
+		})
+		client := NewClient(NewBalancer([]CallTransport{slow, fast}), WithHedging(hedger))
+
+		resp, err := client.Call(context.Background(), &Request{Version: "2.0", Method: "get", ID: NewID(1)})
+		require.NoError(t, err)
+		require.Equal(t, NewID(1), resp.ID)
+		require.Equal(t, []ID{NewID(1)}, slow.ids)
+		if factory == nil {
+			require.Equal(t, []ID{NewID("1.hedge1")}, fast.ids)
+		} else {
+			require.Equal(t, []ID{NewID(101)}, fast.ids)
+		}
+		client.Close()
+	}
+}
@@ -0,0 +1,135 @@
+package jrpc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+/*
+TODO:
+
+*/
+
+type (
+	// Invoker sends req and returns its response.
+	// For notification, returned Response is nil.
+	Invoker func(ctx context.Context, req *Request) (*Response, error)
+
+	// ClientInterceptor is
+	// It wraps Call, Do, Go, Notify, NotifyWithError, and each request of CallBatch and Batch.
+	// The interceptor can modify req, call invoker any number of times, or return without calling it.
+	ClientInterceptor func(ctx context.Context, req *Request, invoker Invoker) (*Response, error)
+
+	// ClientInterceptors is
+	ClientInterceptors []ClientInterceptor
+)
+
+// WithClientInterceptors is
+// Interceptors are called in order, so that the first one is the outermost.
+func WithClientInterceptors(interceptors ...ClientInterceptor) ClientOption {
+	return clientOptionFunc(func(opts *clientOptions) {
+		opts.interceptors = append(opts.interceptors, interceptors...)
+	})
+}
+
+func (ints ClientInterceptors) chained(ctx context.Context, req *Request, invoker Invoker) (*Response, error) {
+	return ints.chain(ctx, req, invoker, 0)
+}
+
+func (ints ClientInterceptors) chain(ctx context.Context, req *Request, invoker Invoker, i int) (*Response, error) {
+	if i == len(ints) {
+		return invoker(ctx, req)
+	}
+	return ints[i](ctx, req, func(ctx2 context.Context, req2 *Request) (*Response, error) {
+		return ints.chain(ctx2, req2, invoker, i+1)
+	})
+}
+
+// callBatchIntercepted passes each request through interceptors.
+// The first invocation of each request is collected into a single batch,
+// and succeeding invocations (such as retry) are sent individually.
+func (c *Client) callBatchIntercepted(ctx context.Context, reqs BatchRequest) (BatchResponse, error) {
+	var wg sync.WaitGroup
+	arrivals := make(chan *batchEntry, len(reqs)) // nil if the chain returned without invocation
+	resps := make([]*Response, len(reqs))
+	errs := make([]error, len(reqs))
+
+	for i, req := range reqs {
+		wg.Add(1)
+		go func(i int, req *Request) {
+			defer wg.Done()
+			var collected int32
+			resps[i], errs[i] = c.options.interceptors.chained(ctx, req, func(ctx context.Context, req *Request) (*Response, error) {
+				if !atomic.CompareAndSwapInt32(&collected, 0, 1) {
+					if req.ID == NoID {
+						return c.notify(ctx, req)
+					}
+					return c.invokeDirect(ctx, req)
+				}
+				e := &batchEntry{
+					ctx:  ctx,
+					req:  req,
+					done: make(chan struct{}),
+				}
+				arrivals <- e
+				select {
+				case <-e.done:
+					return e.resp, e.err
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			})
+			if atomic.CompareAndSwapInt32(&collected, 0, 1) {
+				arrivals <- nil
+			}
+		}(i, req)
+	}
+
+	entries := make([]*batchEntry, 0, len(reqs))
+	for range reqs {
+		if e := <-arrivals; e != nil {
+			entries = append(entries, e)
+		}
+	}
+	var unknown *Response
+	if len(entries) > 0 {
+		batch := make(BatchRequest, len(entries))
+		for i, e := range entries {
+			batch[i] = e.req
+		}
+		batchResp, err := c.callBatch(ctx, batch)
+		unknown = dispatch(entries, batchResp, err)
+		if err != nil {
+			wg.Wait()
+			return nil, err
+		}
+	}
+	wg.Wait()
+
+	result := make(BatchResponse, 0, len(reqs))
+	for i := range reqs {
+		if errs[i] == ErrMissingResponse {
+			continue // same as CallBatch without interceptors
+		} else if errs[i] == nil {
+			if resps[i] != nil && resps[i] != unknown {
+				result = append(result, resps[i])
+			}
+			continue
+		}
+		jrpcErr, ok := errs[i].(*Error)
+		if !ok {
+			return nil, errs[i]
+		} else if reqs[i].ID != NoID {
+			result = append(result, &Response{
+				Version: "2.0",
+				Error:   jrpcErr,
+				ID:      reqs[i].ID,
+			})
+		}
+	}
+	if unknown != nil {
+		result = append(result, unknown)
+	}
+	return result, nil
+}
@@ -0,0 +1,100 @@
+package jrpc
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Interceptors(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+	sizes := recordingServer(serverConn)
+
+	var mu sync.Mutex
+	var log []string
+	logger := func(name string) ClientInterceptor {
+		return func(ctx context.Context, req *Request, invoker Invoker) (*Response, error) {
+			mu.Lock()
+			log = append(log, name+":"+req.Method)
+			mu.Unlock()
+			return invoker(ctx, req)
+		}
+	}
+	rename := func(ctx context.Context, req *Request, invoker Invoker) (*Response, error) {
+		if req.Method == "add" {
+			req.Method = "sum"
+		}
+		return invoker(ctx, req)
+	}
+	block := func(ctx context.Context, req *Request, invoker Invoker) (*Response, error) {
+		if req.Method == "blocked" {
+			return nil, &Error{Code: ErrorCodeInvalidRequest, Message: "blocked"}
+		}
+		return invoker(ctx, req)
+	}
+
+	client := NewClient(NewStreamTransport(clientConn), WithClientInterceptors(logger("first"), logger("second"), rename, block))
+	defer client.Close()
+	ctx := context.Background()
+
+	var result int
+	require.NoError(t, client.Do(ctx, "add", []int{1, 2}, &result))
+	require.Equal(t, 3, result)
+	require.Equal(t, []string{"first:add", "second:add"}, log)
+	require.Equal(t, 0, <-sizes)
+
+	require.NoError(t, client.Notify(ctx, "add", []int{1}))
+	require.Equal(t, 0, <-sizes)
+
+	err := client.Do(ctx, "blocked", nil, nil)
+	require.Equal(t, "blocked", err.(*Error).Message)
+
+	// each request of batch passes through interceptors
+	log = nil
+	resps, err := client.CallBatch(ctx, BatchRequest{
+		{Version: "2.0", Method: "add", Params: rawMessage(`[1,2]`), ID: NewID(1)},
+		{Version: "2.0", Method: "blocked", ID: NewID(2)},
+		{Version: "2.0", Method: "add", Params: rawMessage(`[3]`)},
+		{Version: "2.0", Method: "subtract", Params: rawMessage(`[5,3]`), ID: NewID(3)},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, <-sizes) // blocked request is not sent
+	require.Len(t, log, 8)
+	require.Len(t, resps, 3)
+	m := resps.Map()
+	require.Equal(t, "3", string(*m[NewID(1)].Result))
+	require.Equal(t, "blocked", m[NewID(2)].Error.Message)
+	require.Equal(t, "2", string(*m[NewID(3)].Result))
+}
+
+func TestClient_InterceptorsBatchRetry(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+	sizes := recordingServer(serverConn)
+
+	retry := func(ctx context.Context, req *Request, invoker Invoker) (*Response, error) {
+		resp, err := invoker(ctx, req)
+		if err == nil && resp != nil && resp.Error != nil && req.Method == "unknown" {
+			req.Method = "sum"
+			return invoker(ctx, req)
+		}
+		return resp, err
+	}
+	client := NewClient(NewStreamTransport(clientConn), WithClientInterceptors(retry))
+	defer client.Close()
+
+	resps, err := client.CallBatch(context.Background(), BatchRequest{
+		{Version: "2.0", Method: "sum", Params: rawMessage(`[1,2]`), ID: NewID(1)},
+		{Version: "2.0", Method: "unknown", Params: rawMessage(`[3,4]`), ID: NewID(2)},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, <-sizes)
+	require.Equal(t, 0, <-sizes) // retried individually
+	m := resps.Map()
+	require.Equal(t, "3", string(*m[NewID(1)].Result))
+	require.Equal(t, "7", string(*m[NewID(2)].Result))
+}
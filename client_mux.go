@@ -0,0 +1,111 @@
+package jrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/daichitakahashi/jrpc/internal/pending"
+)
+
+/*
+TODO:
+
+*/
+
+// WithMultiplexing makes Client read responses in a background goroutine,
+// and route them to the callers by their IDs.
+// Any number of goroutines can call concurrently through a single stream transport.
+// It has no effect when the transport already implements CallTransport.
+func WithMultiplexing() ClientOption {
+	return clientOptionFunc(func(opts *clientOptions) {
+		opts.multiplexing = true
+	})
+}
+
+// errNoResponseStream is returned when the transport has no stream to read responses from.
+var errNoResponseStream = errors.New("jrpc: transport has no response stream")
+
+// muxTransport makes ClientTransport with a single response stream into CallTransport.
+type muxTransport struct {
+	ClientTransport
+	framer  Framer
+	wm      sync.Mutex
+	pending *pendingCalls
+	cancel  context.CancelFunc
+}
+
+func newMuxTransport(t ClientTransport, framer Framer) *muxTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &muxTransport{
+		ClientTransport: t,
+		framer:          framer,
+		pending:         newPendingCalls(),
+		cancel:          cancel,
+	}
+	go m.readLoop(ctx)
+	return m
+}
+
+func (m *muxTransport) readLoop(ctx context.Context) {
+	var next func() ([]byte, error)
+	for {
+		recv, updated, shouldClose, err := m.ReceivedResponse(ctx)
+		if err == nil && recv == nil {
+			err = errNoResponseStream
+		}
+		if err != nil {
+			m.pending.Fail(&ConnectionLostError{Err: err})
+			return
+		}
+		if updated || next == nil {
+			next = newMessageReader(recv, m.framer)
+		}
+
+		msg, err := next()
+		if shouldClose {
+			recv.Close()
+		}
+		if err != nil {
+			m.pending.Fail(&ConnectionLostError{Err: err})
+			return
+		}
+		m.pending.deliver(msg)
+	}
+}
+
+// RoundTrip implements CallTransport.
+func (m *muxTransport) RoundTrip(ctx context.Context, r io.Reader, ids []ID) (io.ReadCloser, error) {
+	var pc *pending.Call[ID]
+	if len(ids) > 0 {
+		var err error
+		pc, err = m.pending.Add(ids)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m.wm.Lock()
+	err := m.SendRequest(ctx, r)
+	m.wm.Unlock()
+	if err != nil {
+		if pc != nil {
+			m.pending.Remove(pc)
+		}
+		return nil, err
+	} else if pc == nil {
+		return nil, nil
+	}
+	return m.pending.Wait(ctx, pc)
+}
+
+// Close stops reading responses, and closes underlying transport.
+// Pending calls fail with ErrTransportClosed.
+func (m *muxTransport) Close() error {
+	m.pending.Fail(ErrTransportClosed)
+	m.cancel()
+	return m.ClientTransport.Close()
+}
+
+var _ CallTransport = (*muxTransport)(nil)
@@ -0,0 +1,105 @@
+package jrpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// connTransport is ClientTransport that has a single response stream.
+type connTransport struct {
+	conn    net.Conn
+	started bool
+}
+
+func (ct *connTransport) SendRequest(_ context.Context, r io.Reader) error {
+	_, err := io.Copy(ct.conn, r)
+	return err
+}
+
+func (ct *connTransport) ReceivedResponse(_ context.Context) (recv io.ReadCloser, updated, shouldClose bool, err error) {
+	updated = !ct.started
+	ct.started = true
+	return ct.conn, updated, false, nil
+}
+
+func (ct *connTransport) Close() error {
+	return ct.conn.Close()
+}
+
+func TestClient_Multiplexing(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	client := NewClient(&connTransport{conn: clientConn}, WithMultiplexing(), WithIDFactory(&sequentialIDFactory{}))
+	defer client.Close()
+
+	respond := reverseResponder(serverConn)
+	fakeServer(serverConn, 3, func(lines []string) {
+		for i, line := range lines {
+			if strings.Contains(line, "cancelled") { // never responded
+				lines = append(lines[:i], lines[i+1:]...)
+				break
+			}
+		}
+		respond(lines)
+	})
+
+	var wg sync.WaitGroup
+	for _, method := range []string{"first", "second"} {
+		wg.Add(1)
+		go func(method string) {
+			defer wg.Done()
+			var result string
+			require.NoError(t, client.Do(context.Background(), method, nil, &result))
+			require.Equal(t, method, result)
+		}(method)
+	}
+
+	// cancelled call does not affect others
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, client.Do(ctx, "cancelled", nil, nil))
+	wg.Wait()
+}
+
+func TestClient_MultiplexingClose(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+	client := NewClient(&connTransport{conn: clientConn}, WithMultiplexing())
+
+	fakeServer(serverConn, 1, func([]string) {
+		client.Close()
+	})
+	require.Equal(t, ErrTransportClosed, client.Do(context.Background(), "sum", nil, nil))
+}
+
+func TestClient_Concurrent(t *testing.T) {
+	_, l, _ := startServer(t, "tcp", "127.0.0.1:0")
+
+	for _, opts := range [][]ClientOption{
+		nil, // serialized
+		{WithMultiplexing()},
+	} {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		require.NoError(t, err)
+		client := NewClient(&connTransport{conn: conn}, opts...)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				var result int
+				require.NoError(t, client.Do(context.Background(), "sum", []int{i, 1}, &result))
+				require.Equal(t, i+1, result)
+			}(i)
+		}
+		wg.Wait()
+		client.Close()
+	}
+}
@@ -14,7 +14,16 @@ TODO:
 
 type (
 	clientOptions struct {
-		idFactory IDFactory
+		idFactory    IDFactory
+		framer       Framer
+		multiplexing bool
+		batchWindow  time.Duration
+		batchMaxSize int
+		interceptors ClientInterceptors
+
+		maxBatchLength int
+		maxBatchBytes  int
+		parallelSplit  bool
 	}
 
 	// ClientOption is
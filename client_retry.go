@@ -0,0 +1,161 @@
+package jrpc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+/*
+TODO:
+
+*/
+
+// RetryPolicy is
+// Retried request is sent with the same ID, so that the server can detect duplicates.
+// Zero value of each field is replaced with the value of DefaultRetryPolicy.
+type RetryPolicy struct {
+	MaxAttempts    int           // including the first attempt
+	InitialBackoff time.Duration // backoff before the first retry
+	MaxBackoff     time.Duration
+	Multiplier     float64 // growth rate of backoff
+	Jitter         float64 // backoff is randomly reduced by this fraction at most (0 to 1). negative value disables jitter
+
+	// Retryable reports whether the attempt should be retried.
+	// Either err or resp is set. Default is DefaultRetryable.
+	Retryable func(err error, resp *Response) bool
+
+	// Idempotent reports whether req can be retried.
+	// Default is nil, which means that no request is retried. See IdempotentMethods.
+	Idempotent func(req *Request) bool
+}
+
+// DefaultRetryPolicy is
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+	Retryable:      DefaultRetryable,
+}
+
+// ErrorCodeServerErrorMin and ErrorCodeServerErrorMax are the range of codes reserved for implementation-defined server-errors.
+const (
+	ErrorCodeServerErrorMin ErrorCode = -32099
+	ErrorCodeServerErrorMax ErrorCode = -32000
+)
+
+// DefaultRetryable retries transport errors except cancellation of context,
+// and JSON-RPC errors of the server-error range(-32099 to -32000) such as "server busy".
+// CircuitOpenError and the errors rejecting the request itself, such as HTTP 4xx status
+// except 408 Request Timeout and 429 Too Many Requests, are not retried. See StatusCoder.
+func DefaultRetryable(err error, resp *Response) bool {
+	if err != nil {
+		var openErr *CircuitOpenError
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) &&
+			!errors.Is(err, ErrTransportClosed) && !errors.As(err, &openErr) && !rejected(err)
+	}
+	return resp != nil && resp.Error != nil &&
+		resp.Error.Code >= ErrorCodeServerErrorMin && resp.Error.Code <= ErrorCodeServerErrorMax
+}
+
+// StatusCoder is implemented by transport errors that have the status code of the underlying protocol,
+// such as httpjrpc.StatusError.
+type StatusCoder interface {
+	Status() int
+}
+
+// rejected reports whether err has 4xx status code, that is, the same request will fail again.
+// 408 Request Timeout and 429 Too Many Requests are temporary.
+func rejected(err error) bool {
+	var sc StatusCoder
+	if !errors.As(err, &sc) {
+		return false
+	}
+	status := sc.Status()
+	return status >= 400 && status < 500 && status != 408 && status != 429
+}
+
+// IdempotentMethods returns function for RetryPolicy.Idempotent that accepts requests of methods.
+func IdempotentMethods(methods ...string) func(req *Request) bool {
+	set := make(map[string]struct{}, len(methods))
+	for _, method := range methods {
+		set[method] = struct{}{}
+	}
+	return func(req *Request) bool {
+		_, ok := set[req.Method]
+		return ok
+	}
+}
+
+// WithRetryPolicy installs policy as ClientInterceptor.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return WithClientInterceptors(policy.Interceptor())
+}
+
+// Interceptor returns ClientInterceptor that retries requests according to the policy.
+func (p RetryPolicy) Interceptor() ClientInterceptor {
+	p = p.withDefaults()
+	return func(ctx context.Context, req *Request, invoker Invoker) (*Response, error) {
+		if p.Idempotent == nil || !p.Idempotent(req) {
+			return invoker(ctx, req)
+		}
+		for attempt := 1; ; attempt++ {
+			resp, err := invoker(ctx, req)
+			if attempt >= p.MaxAttempts || !p.Retryable(err, resp) {
+				return resp, err
+			}
+
+			timer := time.NewTimer(p.backoff(attempt))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return resp, err
+			}
+		}
+	}
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	d := DefaultRetryPolicy
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = d.MaxAttempts
+	}
+	if p.InitialBackoff == 0 {
+		p.InitialBackoff = d.InitialBackoff
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = d.MaxBackoff
+	}
+	if p.Multiplier == 0 {
+		p.Multiplier = d.Multiplier
+	}
+	if p.Jitter == 0 {
+		p.Jitter = d.Jitter
+	}
+	if p.Retryable == nil {
+		p.Retryable = d.Retryable
+	}
+	return p
+}
+
+// backoff returns duration to wait before the next attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt-1))
+	if backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		backoff -= backoff * p.Jitter * randomFloat()
+	}
+	return time.Duration(backoff)
+}
+
+func randomFloat() float64 {
+	randMutex.Lock()
+	defer randMutex.Unlock()
+	return float64(src.Int63()) / (1 << 63)
+}
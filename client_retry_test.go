@@ -0,0 +1,149 @@
+package jrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	var mu sync.Mutex
+	var ids []ID
+	failures := 2
+
+	core := NewRepository()
+	core.With(func(ctx context.Context, params *json.RawMessage, info *RequestInfo, handler Handler) (interface{}, *Error) {
+		mu.Lock()
+		defer mu.Unlock()
+		ids = append(ids, info.ID)
+		if failures > 0 {
+			failures--
+			return nil, &Error{Code: ErrorCodeServerErrorMax, Message: "server busy"}
+		}
+		return handler.ServeJSONRPC(ctx, params)
+	})
+	core.Register("get", HandlerFunc(func(context.Context, *json.RawMessage) (interface{}, *Error) {
+		return "ok", nil
+	}), nil, "")
+	core.Register("post", HandlerFunc(func(context.Context, *json.RawMessage) (interface{}, *Error) {
+		return "ok", nil
+	}), nil, "")
+	_, l, _ := startServerWith(t, core, "tcp", "127.0.0.1:0")
+
+	st, err := DialTCP(context.Background(), l.Addr().String())
+	require.NoError(t, err)
+	client := NewClient(st, WithRetryPolicy(RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		Idempotent:     IdempotentMethods("get"),
+	}))
+	defer client.Close()
+	ctx := context.Background()
+
+	var result string
+	require.NoError(t, client.Do(ctx, "get", nil, &result))
+	require.Equal(t, "ok", result)
+	require.Len(t, ids, 3)
+	require.Equal(t, ids[0], ids[1]) // same ID
+	require.Equal(t, ids[0], ids[2])
+
+	// not idempotent
+	ids, failures = nil, 1
+	err = client.Do(ctx, "post", nil, &result)
+	require.Equal(t, "server busy", err.(*Error).Message)
+	require.Len(t, ids, 1)
+
+	// give up
+	ids, failures = nil, 5
+	err = client.Do(ctx, "get", nil, &result)
+	require.Equal(t, "server busy", err.(*Error).Message)
+	require.Len(t, ids, 3)
+}
+
+func TestRetryPolicy_TransportError(t *testing.T) {
+	var attempts int
+	policy := RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: time.Millisecond,
+		Idempotent:     func(*Request) bool { return true },
+	}
+	failing := func(context.Context, *Request) (*Response, error) {
+		attempts++
+		return nil, &ConnectionLostError{Err: errors.New("reset")}
+	}
+	req, _ := NewRequest("get", nil, NewID(1))
+
+	_, err := policy.Interceptor()(context.Background(), req, failing)
+	require.Error(t, err)
+	require.Equal(t, 4, attempts)
+
+	// cancellation is not retried
+	attempts = 0
+	_, err = policy.Interceptor()(context.Background(), req, func(context.Context, *Request) (*Response, error) {
+		attempts++
+		return nil, context.DeadlineExceeded
+	})
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.Equal(t, 1, attempts)
+
+	// backoff is stopped by context
+	attempts = 0
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	policy.InitialBackoff = time.Second
+	_, err = policy.Interceptor()(ctx, req, failing)
+	require.Error(t, err)
+	require.Equal(t, 1, attempts)
+}
+
+type statusError int
+
+func (e statusError) Error() string { return "status error" }
+
+func (e statusError) Status() int { return int(e) }
+
+func TestDefaultRetryable(t *testing.T) {
+	for err, retryable := range map[error]bool{
+		&ConnectionLostError{Err: errors.New("reset")}: true,
+		context.Canceled:                            false,
+		&CircuitOpenError{Method: "a"}:              false,
+		statusError(400):                            false,
+		statusError(404):                            false,
+		statusError(408):                            true,
+		statusError(429):                            true,
+		statusError(503):                            true,
+		fmt.Errorf("wrapped: %w", statusError(403)): false,
+	} {
+		require.Equal(t, retryable, DefaultRetryable(err, nil), err)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Jitter:         0.5,
+	}.withDefaults()
+	for attempt, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max *= time.Millisecond
+		backoff := p.backoff(attempt + 1)
+		require.True(t, backoff <= max && backoff >= max/2, backoff)
+	}
+}
+
+func TestRetryPolicy_Backoff_NoJitter(t *testing.T) {
+	p := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Jitter:         -1,
+	}.withDefaults()
+	for attempt, want := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		require.Equal(t, want*time.Millisecond, p.backoff(attempt+1))
+	}
+}
@@ -0,0 +1,103 @@
+package jrpc
+
+import (
+	"context"
+
+	"golang.org/x/sync/errgroup"
+)
+
+/*
+TODO:
+
+*/
+
+// WithBatchLimit makes Client split batch request that exceeds maxLength requests or maxBytes of encoded size
+// into several batches. Responses of them are merged into a single BatchResponse.
+// Zero or negative value means no limit. A request larger than maxBytes is sent alone as a batch.
+// It is applied to CallBatch, Batch, NewBatch and WithAutoBatching.
+func WithBatchLimit(maxLength, maxBytes int) ClientOption {
+	return clientOptionFunc(func(opts *clientOptions) {
+		opts.maxBatchLength = maxLength
+		opts.maxBatchBytes = maxBytes
+	})
+}
+
+// WithParallelBatches makes Client send split batches in parallel. By default, they are sent sequentially.
+func WithParallelBatches() ClientOption {
+	return clientOptionFunc(func(opts *clientOptions) {
+		opts.parallelSplit = true
+	})
+}
+
+func (c *Client) callSplitBatch(ctx context.Context, reqs BatchRequest) (BatchResponse, error) {
+	chunks, err := splitBatch(reqs, c.options.maxBatchLength, c.options.maxBatchBytes)
+	if err != nil {
+		return nil, err
+	} else if len(chunks) == 1 {
+		return c.sendBatch(ctx, chunks[0])
+	}
+
+	results := make([]BatchResponse, len(chunks))
+	if c.options.parallelSplit {
+		eg, ctx := errgroup.WithContext(ctx)
+		for i, chunk := range chunks {
+			i, chunk := i, chunk
+			eg.Go(func() (err error) {
+				results[i], err = c.sendBatch(ctx, chunk)
+				return err
+			})
+		}
+		if err := eg.Wait(); err != nil {
+			return nil, err
+		}
+	} else {
+		for i, chunk := range chunks {
+			results[i], err = c.sendBatch(ctx, chunk)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	merged := make(BatchResponse, 0, len(reqs))
+	for _, resps := range results {
+		merged = append(merged, resps...)
+	}
+	return merged, nil
+}
+
+// splitBatch splits reqs into batches that have at most maxLength requests and maxBytes of encoded size.
+func splitBatch(reqs BatchRequest, maxLength, maxBytes int) ([]BatchRequest, error) {
+	var sizes []int
+	if maxBytes > 0 {
+		sizes = make([]int, len(reqs))
+		buf := bufferpool.Get()
+		defer buf.Free()
+		for i, req := range reqs {
+			buf.Reset()
+			if err := req.encodeTo(buf); err != nil {
+				return nil, err
+			}
+			sizes[i] = buf.Len()
+		}
+	}
+
+	var chunks []BatchRequest
+	start, size := 0, 1 // '['
+	for i := range reqs {
+		n := i - start
+		if n > 0 {
+			full := maxLength > 0 && n >= maxLength
+			// ',' or ']' follows each request
+			full = full || (maxBytes > 0 && size+sizes[i]+1 > maxBytes)
+			if full {
+				chunks = append(chunks, reqs[start:i])
+				start, size = i, 1
+			}
+		}
+		if maxBytes > 0 {
+			size += sizes[i] + 1
+		}
+	}
+	return append(chunks, reqs[start:]), nil
+}
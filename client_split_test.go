@@ -0,0 +1,81 @@
+package jrpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitBatch(t *testing.T) {
+	reqs := make(BatchRequest, 5)
+	for i := range reqs {
+		reqs[i], _ = NewRequest("sum", []int{i}, NewID(i))
+	}
+	buf, _ := reqs[0].encode()
+	one := buf.Len() + 1 // with ',' or ']'
+	buf.Free()
+	lengths := func(chunks []BatchRequest) []int {
+		var n []int
+		for _, chunk := range chunks {
+			n = append(n, len(chunk))
+		}
+		return n
+	}
+
+	for _, c := range []struct {
+		maxLength, maxBytes int
+		expected            []int
+	}{
+		{maxLength: 2, expected: []int{2, 2, 1}},
+		{maxLength: 5, expected: []int{5}},
+		{maxBytes: 1 + one*2, expected: []int{2, 2, 1}},
+		{maxBytes: 1 + one*2 - 1, expected: []int{1, 1, 1, 1, 1}},
+		{maxBytes: 10, expected: []int{1, 1, 1, 1, 1}}, // too large request is sent alone
+		{maxLength: 2, maxBytes: 1000, expected: []int{2, 2, 1}},
+	} {
+		t.Run(fmt.Sprint(c.maxLength, c.maxBytes), func(t *testing.T) {
+			chunks, err := splitBatch(reqs, c.maxLength, c.maxBytes)
+			require.NoError(t, err)
+			require.Equal(t, c.expected, lengths(chunks))
+		})
+	}
+
+	buf, _ = reqs[:2].encode()
+	require.Equal(t, 1+one*2, buf.Len())
+	buf.Free()
+}
+
+func TestClient_SplitBatch(t *testing.T) {
+	for _, opts := range [][]ClientOption{
+		{WithBatchLimit(2, 0)},
+		{WithBatchLimit(2, 0), WithParallelBatches()},
+	} {
+		clientConn, serverConn := net.Pipe()
+		sizes := recordingServer(serverConn)
+		client := NewClient(NewStreamTransport(clientConn), opts...)
+
+		reqs := BatchRequest{
+			{Version: "2.0", Method: "sum", Params: rawMessage(`[1]`), ID: NewID(1)},
+			{Version: "2.0", Method: "sum", Params: rawMessage(`[2]`), ID: NewID(2)},
+			{Version: "2.0", Method: "sum", Params: rawMessage(`[3]`)},
+			{Version: "2.0", Method: "unknown", ID: NewID(4)},
+			{Version: "2.0", Method: "sum", Params: rawMessage(`[5]`), ID: NewID(5)},
+		}
+		resps, err := client.CallBatch(context.Background(), reqs)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []int{2, 2, 1}, []int{<-sizes, <-sizes, <-sizes})
+
+		m := resps.Map()
+		require.Len(t, m, 4)
+		require.Equal(t, "1", string(*m[NewID(1)].Result))
+		require.Equal(t, "2", string(*m[NewID(2)].Result))
+		require.Equal(t, ErrorCodeMethodNotFound, m[NewID(4)].Error.Code)
+		require.Equal(t, "5", string(*m[NewID(5)].Result))
+
+		client.Close()
+		serverConn.Close()
+	}
+}
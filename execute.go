@@ -15,35 +15,41 @@ TODO:
 
 // Execute is
 func (c *Core) Execute(ctx context.Context, requests []*Request, batch bool) ([]*Response, error) {
+	resps := make([]*Response, 0, len(requests))
+	err := c.ExecuteFunc(ctx, requests, batch, func(resp *Response) error {
+		resps = append(resps, resp)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resps, nil
+}
+
+// ExecuteFunc is like Execute, but passes each response to fn as soon as it is ready,
+// in the order of completion. Responses for notifications are not passed.
+// fn is not called concurrently. If fn returns error, the rest of requests are cancelled
+// and ExecuteFunc returns the error.
+func (c *Core) ExecuteFunc(ctx context.Context, requests []*Request, batch bool, fn func(resp *Response) error) error {
 	if len(requests) == 0 {
 		resp := (*Request)(nil).toResponse()
 		resp.Error = ErrInvalidRequest(nil)
 		resp.ID = UnknownID
-		return []*Response{
-			resp,
-		}, nil
+		return fn(resp)
 	}
 
-	resps := make([]*Response, 0, len(requests))
-
-	if len(requests) == 1 {
-		resp, err := c.DoMethod(ctx, requests[0], batch)
-		if err != nil {
-			return nil, err
-		} else if resp.isSend() {
-			resps = append(resps, resp)
-		}
-		return resps, nil
-	} else if c.options.disableConcurrentCall {
+	if len(requests) == 1 || c.options.disableConcurrentCall {
 		for _, req := range requests {
 			resp, err := c.DoMethod(ctx, req, batch)
 			if err != nil {
-				return nil, err
+				return err
 			} else if resp.isSend() {
-				resps = append(resps, resp)
+				if err = fn(resp); err != nil {
+					return err
+				}
 			}
 		}
-		return resps, nil
+		return nil
 	}
 
 	eg, ctxBatch := errgroup.WithContext(ctx)
@@ -57,17 +63,13 @@ func (c *Core) Execute(ctx context.Context, requests []*Request, batch bool) ([]
 				return err
 			} else if resp.isSend() {
 				m.Lock()
-				resps = append(resps, resp)
-				m.Unlock()
+				defer m.Unlock()
+				return fn(resp)
 			}
 			return nil
 		})
 	}
-	err := eg.Wait()
-	if err != nil {
-		return nil, err
-	}
-	return resps, nil
+	return eg.Wait()
 }
 
 // DoMethod is
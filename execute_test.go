@@ -2,6 +2,7 @@ package jrpc
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -306,3 +307,33 @@ func TestCallRPCInternal(t *testing.T) {
 		})
 	}
 }
+
+func TestCore_ExecuteFunc(t *testing.T) {
+	repository := newMock()
+	reqs := make([]*Request, 0, 4)
+	for _, ms := range []int{60, 20, 40} {
+		reqs = append(reqs, &Request{
+			Version: "2.0",
+			Method:  "wait.sayHello",
+			ID:      NewID(ms),
+		})
+	}
+	reqs = append(reqs, &Request{
+		Version: "2.0",
+		Method:  "wait.sayHello",
+	})
+
+	var ids []ID
+	err := repository.ExecuteFunc(context.Background(), reqs, true, func(resp *Response) error {
+		ids = append(ids, resp.ID)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []ID{NewID(20), NewID(40), NewID(60)}, ids)
+
+	stop := errors.New("stop")
+	err = repository.ExecuteFunc(context.Background(), reqs, true, func(resp *Response) error {
+		return stop
+	})
+	require.Equal(t, stop, err)
+}
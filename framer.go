@@ -0,0 +1,377 @@
+package jrpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/*
+TODO:
+
+*/
+
+type (
+	// Framer determines how the boundary of each message is expressed in a stream.
+	// Decoder, Encoder, ServeStream and Client can be parameterized by Framer.
+	Framer interface {
+		NewFrameReader(r io.Reader) FrameReader
+		NewFrameWriter(w io.Writer) FrameWriter
+	}
+
+	// FrameReader reads a message at a time.
+	// ReadFrame returns io.EOF only when the stream ends cleanly before the next frame begins,
+	// and returns *FrameError when the boundary of the frame is broken.
+	FrameReader interface {
+		ReadFrame() ([]byte, error)
+	}
+
+	// FrameWriter writes a message at a time.
+	// WriteFrame should write the whole frame by a single call of Write.
+	FrameWriter interface {
+		WriteFrame(msg []byte) error
+	}
+)
+
+// MaxFrameLength is upper limit of the length of a single framed message.
+// A frame that declares longer length is rejected with FrameError before its body is read.
+const MaxFrameLength = 64 << 20
+
+// FrameError represents malformed frame header.
+// Once FrameError occurred, the boundary of the next message is unknown,
+// so the stream cannot be read any more.
+type FrameError struct {
+	Header string
+	Reason string
+}
+
+func (fe *FrameError) Error() string {
+	if fe.Header == "" {
+		return "jrpc: malformed frame: " + fe.Reason
+	}
+	return "jrpc: malformed frame: " + fe.Reason + ": " + strconv.Quote(fe.Header)
+}
+
+func toBufioReader(r io.Reader) *bufio.Reader {
+	if br, ok := r.(*bufio.Reader); ok {
+		return br
+	}
+	return bufio.NewReader(r)
+}
+
+// frameWriter writes each frame from pooled Buffer by a single call of Write.
+type frameWriter struct {
+	w      io.Writer
+	header func(buf *Buffer, msg []byte)
+	footer string
+}
+
+func (fw *frameWriter) WriteFrame(msg []byte) error {
+	buf := bufferpool.Get()
+	defer buf.Free()
+	if fw.header != nil {
+		fw.header(buf, msg)
+	}
+	buf.Write(msg)
+	buf.AppendString(fw.footer)
+	_, err := fw.w.Write(buf.Bytes())
+	return err
+}
+
+// NewlineFramer delimits messages by '\n'.
+// Blank lines between messages are ignored.
+// Unlike NewDecoder, it requires that message does not contain raw newline.
+type NewlineFramer struct{}
+
+// NewFrameReader is
+func (NewlineFramer) NewFrameReader(r io.Reader) FrameReader {
+	return &newlineFrameReader{
+		r: toBufioReader(r),
+	}
+}
+
+// NewFrameWriter is
+func (NewlineFramer) NewFrameWriter(w io.Writer) FrameWriter {
+	return &frameWriter{
+		w:      w,
+		footer: "\n",
+	}
+}
+
+type newlineFrameReader struct {
+	r *bufio.Reader
+}
+
+func (nfr *newlineFrameReader) ReadFrame() ([]byte, error) {
+	var line []byte
+	for {
+		b, err := nfr.r.ReadSlice('\n')
+		if len(line)+len(b) > MaxFrameLength {
+			return nil, &FrameError{
+				Reason: "too long frame",
+			}
+		}
+		line = append(line, b...)
+		switch err {
+		case bufio.ErrBufferFull:
+			continue
+		case io.EOF:
+			if len(bytes.TrimSpace(line)) == 0 {
+				return nil, io.EOF
+			}
+			return line, nil // last line without newline
+		case nil:
+			if len(bytes.TrimSpace(line)) == 0 {
+				line = line[:0]
+				continue
+			}
+			return line, nil
+		default:
+			return nil, err
+		}
+	}
+}
+
+const headerContentLength = "Content-Length"
+
+// HeaderFramer precedes each message with "Content-Length: N\r\n\r\n" header,
+// as in Language Server Protocol and Debug Adapter Protocol.
+// Header fields other than Content-Length are ignored.
+type HeaderFramer struct{}
+
+// NewFrameReader is
+func (HeaderFramer) NewFrameReader(r io.Reader) FrameReader {
+	return &headerFrameReader{
+		r: toBufioReader(r),
+	}
+}
+
+// NewFrameWriter is
+func (HeaderFramer) NewFrameWriter(w io.Writer) FrameWriter {
+	return &frameWriter{
+		w: w,
+		header: func(buf *Buffer, msg []byte) {
+			buf.AppendString(headerContentLength)
+			buf.AppendString(": ")
+			buf.AppendInt(int64(len(msg)))
+			buf.AppendString("\r\n\r\n")
+		},
+	}
+}
+
+type headerFrameReader struct {
+	r *bufio.Reader
+}
+
+func (hfr *headerFrameReader) ReadFrame() ([]byte, error) {
+	length := -1
+	for first := true; ; first = false {
+		line, err := hfr.r.ReadSlice('\n')
+		switch {
+		case err == bufio.ErrBufferFull:
+			return nil, &FrameError{
+				Header: string(line[:min(len(line), 32)]),
+				Reason: "header line too long",
+			}
+		case err == io.EOF && first && len(line) == 0:
+			return nil, io.EOF
+		case err == io.EOF:
+			return nil, io.ErrUnexpectedEOF
+		case err != nil:
+			return nil, err
+		}
+
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) == 0 { // end of header part
+			break
+		}
+
+		i := bytes.IndexByte(line, ':')
+		if i <= 0 || !isToken(line[:i]) {
+			return nil, &FrameError{
+				Header: string(line),
+				Reason: "invalid header field",
+			}
+		}
+		name := string(line[:i])
+		if !strings.EqualFold(name, headerContentLength) {
+			continue // such as Content-Type
+		}
+		value := string(bytes.TrimSpace(line[i+1:]))
+		n, err := strconv.ParseUint(value, 10, 63)
+		if err != nil {
+			return nil, &FrameError{
+				Header: string(line),
+				Reason: "invalid Content-Length",
+			}
+		} else if length >= 0 && int64(length) != int64(n) {
+			return nil, &FrameError{
+				Header: string(line),
+				Reason: "conflicting Content-Length",
+			}
+		} else if n > MaxFrameLength {
+			return nil, &FrameError{
+				Header: string(line),
+				Reason: "too long frame",
+			}
+		}
+		length = int(n)
+	}
+
+	if length < 0 {
+		return nil, &FrameError{
+			Reason: "missing Content-Length",
+		}
+	}
+	return readFull(hfr.r, length)
+}
+
+// isToken reports whether b is valid header field name. (RFC 7230 section 3.2.6)
+func isToken(b []byte) bool {
+	for _, c := range b {
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`"(),/:;<=>?@[\]{}`, c) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// LengthPrefixFramer precedes each message with its length as 4-byte big-endian unsigned integer.
+type LengthPrefixFramer struct{}
+
+// NewFrameReader is
+func (LengthPrefixFramer) NewFrameReader(r io.Reader) FrameReader {
+	return &lengthPrefixFrameReader{
+		r: toBufioReader(r),
+	}
+}
+
+// NewFrameWriter is
+func (LengthPrefixFramer) NewFrameWriter(w io.Writer) FrameWriter {
+	return &frameWriter{
+		w: w,
+		header: func(buf *Buffer, msg []byte) {
+			var prefix [4]byte
+			binary.BigEndian.PutUint32(prefix[:], uint32(len(msg)))
+			buf.Write(prefix[:])
+		},
+	}
+}
+
+type lengthPrefixFrameReader struct {
+	r *bufio.Reader
+}
+
+func (lfr *lengthPrefixFrameReader) ReadFrame() ([]byte, error) {
+	var prefix [4]byte
+	_, err := io.ReadFull(lfr.r, prefix[:])
+	if err != nil {
+		return nil, err // io.EOF or io.ErrUnexpectedEOF
+	}
+	n := binary.BigEndian.Uint32(prefix[:])
+	if n > MaxFrameLength {
+		return nil, &FrameError{
+			Header: string(prefix[:]),
+			Reason: "too long frame",
+		}
+	}
+	return readFull(lfr.r, int(n))
+}
+
+// NetstringFramer expresses each message as netstring, "<length>:<message>,".
+// See https://cr.yp.to/proto/netstrings.txt
+type NetstringFramer struct{}
+
+// NewFrameReader is
+func (NetstringFramer) NewFrameReader(r io.Reader) FrameReader {
+	return &netstringFrameReader{
+		r: toBufioReader(r),
+	}
+}
+
+// NewFrameWriter is
+func (NetstringFramer) NewFrameWriter(w io.Writer) FrameWriter {
+	return &frameWriter{
+		w: w,
+		header: func(buf *Buffer, msg []byte) {
+			buf.AppendInt(int64(len(msg)))
+			buf.AppendByte(':')
+		},
+		footer: ",",
+	}
+}
+
+type netstringFrameReader struct {
+	r *bufio.Reader
+}
+
+// maxNetstringDigits is the number of digits of MaxFrameLength
+const maxNetstringDigits = 8
+
+func (nfr *netstringFrameReader) ReadFrame() ([]byte, error) {
+	var n int
+	var digits []byte
+	for {
+		c, err := nfr.r.ReadByte()
+		if err == io.EOF && len(digits) == 0 {
+			return nil, io.EOF
+		} else if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		} else if err != nil {
+			return nil, err
+		}
+
+		if c == ':' && len(digits) > 0 {
+			break
+		} else if c < '0' || c > '9' ||
+			len(digits) == maxNetstringDigits ||
+			(len(digits) == 1 && digits[0] == '0') { // leading zeros are prohibited
+			return nil, &FrameError{
+				Header: string(append(digits, c)),
+				Reason: "invalid netstring length",
+			}
+		}
+		digits = append(digits, c)
+		n = n*10 + int(c-'0')
+	}
+	if n > MaxFrameLength {
+		return nil, &FrameError{
+			Header: string(digits),
+			Reason: "too long frame",
+		}
+	}
+
+	frame, err := readFull(nfr.r, n+1)
+	if err != nil {
+		return nil, err
+	}
+	if frame[n] != ',' {
+		return nil, &FrameError{
+			Header: string(digits),
+			Reason: "missing trailing comma of netstring",
+		}
+	}
+	return frame[:n], nil
+}
+
+func readFull(r io.Reader, length int) ([]byte, error) {
+	frame := make([]byte, length)
+	_, err := io.ReadFull(r, frame)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+var (
+	_ Framer = NewlineFramer{}
+	_ Framer = HeaderFramer{}
+	_ Framer = LengthPrefixFramer{}
+	_ Framer = NetstringFramer{}
+)
@@ -0,0 +1,405 @@
+package jrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaderFramer(t *testing.T) {
+	testcases := []struct {
+		src    string
+		frame  string
+		err    error
+		reason string
+		desc   string
+	}{
+		{
+			src:   "Content-Length: 7\r\n\r\n{\"a\":1}",
+			frame: `{"a":1}`,
+			desc:  "success",
+		}, {
+			src:   "Content-Type: application/vscode-jsonrpc; charset=utf-8\r\ncontent-length:7\r\n\r\n{\"a\":1}",
+			frame: `{"a":1}`,
+			desc:  "other header and case insensitive name",
+		}, {
+			src:   "Content-Length: 7\n\n{\"a\":1}",
+			frame: `{"a":1}`,
+			desc:  "LF only",
+		}, {
+			src:  "",
+			err:  io.EOF,
+			desc: "EOF",
+		}, {
+			src:  "Content-Length: 7\r\n",
+			err:  io.ErrUnexpectedEOF,
+			desc: "EOF in header",
+		}, {
+			src:  "Content-Length: 7\r\n\r\n{\"a\"",
+			err:  io.ErrUnexpectedEOF,
+			desc: "EOF in body",
+		}, {
+			src:    "Content-Type: application/json\r\n\r\n{}",
+			reason: "missing Content-Length",
+			desc:   "missing Content-Length",
+		}, {
+			src:    "Content-Length: abc\r\n\r\n{}",
+			reason: "invalid Content-Length",
+			desc:   "not a number",
+		}, {
+			src:    "Content-Length: -2\r\n\r\n{}",
+			reason: "invalid Content-Length",
+			desc:   "negative",
+		}, {
+			src:    "Content-Length: 2\r\nContent-Length: 3\r\n\r\n{}",
+			reason: "conflicting Content-Length",
+			desc:   "conflicting",
+		}, {
+			src:    "Content-Length: 999999999999\r\n\r\n{}",
+			reason: "too long frame",
+			desc:   "too long",
+		}, {
+			src:    "{\"jsonrpc\":\"2.0\"}\r\n\r\n",
+			reason: "invalid header field",
+			desc:   "no header",
+		}, {
+			src:    "Content-Length: 2" + strings.Repeat(" ", 5000) + "\r\n\r\n{}",
+			reason: "header line too long",
+			desc:   "too long line",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.desc, func(t *testing.T) {
+			frame, err := HeaderFramer{}.NewFrameReader(strings.NewReader(testcase.src)).ReadFrame()
+			if testcase.reason != "" {
+				require.IsType(t, &FrameError{}, err)
+				require.Equal(t, testcase.reason, err.(*FrameError).Reason)
+				return
+			}
+			require.Equal(t, testcase.err, err)
+			require.Equal(t, testcase.frame, string(frame))
+		})
+	}
+}
+
+func TestHeaderFramer_SmallBuffer(t *testing.T) {
+	// the buffer is smaller than the header shown in FrameError
+	r := bufio.NewReaderSize(strings.NewReader("Content-Length: 2"+strings.Repeat(" ", 100)+"\r\n\r\n{}"), 16)
+	_, err := HeaderFramer{}.NewFrameReader(r).ReadFrame()
+	require.IsType(t, &FrameError{}, err)
+	require.Equal(t, "header line too long", err.(*FrameError).Reason)
+	require.Equal(t, "Content-Length: ", err.(*FrameError).Header)
+}
+
+func TestDecoder_DecodeHeader(t *testing.T) {
+	buf := bufferpool.Get()
+	defer buf.Free()
+	fw := HeaderFramer{}.NewFrameWriter(buf)
+	fw.WriteFrame([]byte(`{"jsonrpc":"2.0","method":"sum","params":[1]}`))
+	fw.WriteFrame([]byte(`{"a"`))
+	fw.WriteFrame([]byte(`[{"jsonrpc":"2.0","method":"a","id":1},{"jsonrpc":"2.0","id":2}]`))
+	fw.WriteFrame([]byte(`{"jsonrpc":"2.0","method":999}`))
+	buf.AppendString("Content-Length: x\r\n\r\n")
+	dec := NewHeaderDecoder(bytes.NewReader(buf.Bytes()))
+
+	reqs, batch, err := dec.Decode(nil)
+	require.NoError(t, err)
+	require.False(t, batch)
+	require.Len(t, reqs, 1)
+	require.Equal(t, "sum", reqs[0].Method)
+
+	reqs, batch, err = dec.Decode(Calibrate(reqs, 3))
+	require.NoError(t, err)
+	require.False(t, batch)
+	require.Len(t, reqs, 1)
+	require.Equal(t, rpcParseError, reqs[0].Method)
+	require.NoError(t, dec.Err(), "parse error in the frame does not break the stream")
+
+	reqs, batch, err = dec.Decode(Calibrate(reqs, 3))
+	require.NoError(t, err)
+	require.True(t, batch)
+	require.Len(t, reqs, 2)
+	require.Equal(t, NewID(1), reqs[0].ID)
+	require.Equal(t, NewID(2), reqs[1].ID)
+
+	reqs, batch, err = dec.Decode(Calibrate(reqs, 3))
+	require.NoError(t, err)
+	require.False(t, batch)
+	require.Len(t, reqs, 1)
+	require.Equal(t, rpcInvalidRequest, reqs[0].Method)
+
+	reqs, batch, err = dec.Decode(Calibrate(reqs, 3))
+	require.NoError(t, err)
+	require.Len(t, reqs, 1)
+	require.Equal(t, rpcParseError, reqs[0].Method)
+	require.IsType(t, &FrameError{}, dec.Err())
+
+	reqs, _, err = dec.Decode(Calibrate(reqs, 3))
+	require.Len(t, reqs, 0)
+	require.IsType(t, &FrameError{}, err)
+}
+
+func TestEncoder_EncodeHeader(t *testing.T) {
+	var b bytes.Buffer
+	enc := NewHeaderEncoder(&b)
+
+	resp1 := &Response{
+		Version: "2.0",
+		ID:      NewID(1),
+	}
+	resp1.EncodeAndSetResult(true)
+	resp2 := &Response{
+		Version: "2.0",
+		Error:   ErrMethodNotFound(),
+		ID:      NewID(2),
+	}
+
+	require.NoError(t, enc.Encode([]*Response{resp1}, false))
+	require.Equal(t, "Content-Length: 38\r\n\r\n"+`{"jsonrpc":"2.0","result":true,"id":1}`, b.String())
+	b.Reset()
+
+	require.NoError(t, enc.Encode([]*Response{resp1, resp2}, true))
+	body := `[{"jsonrpc":"2.0","result":true,"id":1},{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":2}]`
+	frame, err := HeaderFramer{}.NewFrameReader(&b).ReadFrame()
+	require.NoError(t, err)
+	require.Equal(t, body, string(frame))
+}
+
+func TestServeStream_HeaderFraming(t *testing.T) {
+	server, conn := net.Pipe()
+	defer conn.Close()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- ServeStream(context.Background(), server, newMock(), WithHeaderFraming())
+		server.Close()
+	}()
+
+	client := NewClient(&transport{
+		send: func(ctx context.Context, r io.Reader) error {
+			_, err := io.Copy(conn, r)
+			return err
+		},
+		recv: func(ctx context.Context) (io.ReadCloser, bool, bool, error) {
+			return ioutil.NopCloser(conn), false, false, nil
+		},
+	}, WithHeaderFraming())
+
+	var result int
+	require.NoError(t, client.Do(context.Background(), "sum", []int{1, 2, 3}, &result))
+	require.Equal(t, 6, result)
+
+	// notification is not responded
+	require.NoError(t, client.Notify(context.Background(), "sum", []int{1}))
+
+	resps, err := client.CallBatch(context.Background(), BatchRequest{
+		{Version: "2.0", Method: "sum", ID: NewID(1)},
+		{Version: "2.0", Method: "unknown", ID: NewID(2)},
+	})
+	require.NoError(t, err)
+	require.Len(t, resps, 2)
+	resp, ok := resps.GetFromID(NewID(2))
+	require.True(t, ok)
+	require.Equal(t, ErrorCodeMethodNotFound, resp.Error.Code)
+
+	// malformed header is responded with Parse error, then the stream is closed
+	_, err = conn.Write([]byte("Content-Length: none\r\n\r\n"))
+	require.NoError(t, err)
+	frame, err := HeaderFramer{}.NewFrameReader(conn).ReadFrame()
+	require.NoError(t, err)
+	require.Contains(t, string(frame), `"code":-32700`)
+	require.IsType(t, &FrameError{}, <-served)
+}
+
+func TestFramer_RoundTrip(t *testing.T) {
+	msgs := []string{`{"jsonrpc":"2.0","method":"a"}`, `[]`, ``, "{\n}"}
+	framers := map[string]Framer{
+		"header":        HeaderFramer{},
+		"length prefix": LengthPrefixFramer{},
+		"netstring":     NetstringFramer{},
+	}
+	for name, framer := range framers {
+		t.Run(name, func(t *testing.T) {
+			var b bytes.Buffer
+			fw := framer.NewFrameWriter(&b)
+			for _, msg := range msgs {
+				require.NoError(t, fw.WriteFrame([]byte(msg)))
+			}
+			fr := framer.NewFrameReader(&b)
+			for _, msg := range msgs {
+				frame, err := fr.ReadFrame()
+				require.NoError(t, err)
+				require.Equal(t, msg, string(frame))
+			}
+			_, err := fr.ReadFrame()
+			require.Equal(t, io.EOF, err)
+		})
+	}
+}
+
+func TestNewlineFramer(t *testing.T) {
+	var b bytes.Buffer
+	fw := NewlineFramer{}.NewFrameWriter(&b)
+	require.NoError(t, fw.WriteFrame([]byte(`{"a":1}`)))
+	require.Equal(t, "{\"a\":1}\n", b.String())
+
+	fr := NewlineFramer{}.NewFrameReader(strings.NewReader("{\"a\":1}\n\n\r\n" + strings.Repeat(" ", 5000) + "[]\n{}"))
+	frame, err := fr.ReadFrame()
+	require.NoError(t, err)
+	require.Equal(t, "{\"a\":1}\n", string(frame))
+	frame, err = fr.ReadFrame()
+	require.NoError(t, err)
+	require.Equal(t, strings.Repeat(" ", 5000)+"[]\n", string(frame))
+	frame, err = fr.ReadFrame()
+	require.NoError(t, err)
+	require.Equal(t, "{}", string(frame), "last line without newline")
+	_, err = fr.ReadFrame()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestLengthPrefixFramer(t *testing.T) {
+	testcases := []struct {
+		src    string
+		err    error
+		reason string
+		desc   string
+	}{
+		{
+			src:  "\x00\x00",
+			err:  io.ErrUnexpectedEOF,
+			desc: "EOF in prefix",
+		}, {
+			src:  "\x00\x00\x00\x05{}",
+			err:  io.ErrUnexpectedEOF,
+			desc: "EOF in body",
+		}, {
+			src:    "\xff\xff\xff\xff{}",
+			reason: "too long frame",
+			desc:   "too long",
+		},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.desc, func(t *testing.T) {
+			_, err := LengthPrefixFramer{}.NewFrameReader(strings.NewReader(testcase.src)).ReadFrame()
+			if testcase.reason != "" {
+				require.IsType(t, &FrameError{}, err)
+				require.Equal(t, testcase.reason, err.(*FrameError).Reason)
+				return
+			}
+			require.Equal(t, testcase.err, err)
+		})
+	}
+}
+
+func TestNetstringFramer(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, NetstringFramer{}.NewFrameWriter(&b).WriteFrame([]byte(`{"a":1}`)))
+	require.Equal(t, `7:{"a":1},`, b.String())
+
+	testcases := []struct {
+		src    string
+		err    error
+		reason string
+		desc   string
+	}{
+		{
+			src:  "2:{",
+			err:  io.ErrUnexpectedEOF,
+			desc: "EOF in body",
+		}, {
+			src:  "12",
+			err:  io.ErrUnexpectedEOF,
+			desc: "EOF in length",
+		}, {
+			src:    ":{},",
+			reason: "invalid netstring length",
+			desc:   "empty length",
+		}, {
+			src:    "02:{},",
+			reason: "invalid netstring length",
+			desc:   "leading zero",
+		}, {
+			src:    "x:{},",
+			reason: "invalid netstring length",
+			desc:   "not a number",
+		}, {
+			src:    "999999999:{},",
+			reason: "invalid netstring length",
+			desc:   "too many digits",
+		}, {
+			src:    "99999999:{},",
+			reason: "too long frame",
+			desc:   "too long",
+		}, {
+			src:    "2:{};",
+			reason: "missing trailing comma of netstring",
+			desc:   "missing comma",
+		},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.desc, func(t *testing.T) {
+			_, err := NetstringFramer{}.NewFrameReader(strings.NewReader(testcase.src)).ReadFrame()
+			if testcase.reason != "" {
+				require.IsType(t, &FrameError{}, err)
+				require.Equal(t, testcase.reason, err.(*FrameError).Reason)
+				return
+			}
+			require.Equal(t, testcase.err, err)
+		})
+	}
+}
+
+func TestServeStream_Framer(t *testing.T) {
+	framers := map[string]Framer{
+		"newline":       NewlineFramer{},
+		"length prefix": LengthPrefixFramer{},
+		"netstring":     NetstringFramer{},
+	}
+	for name, framer := range framers {
+		t.Run(name, func(t *testing.T) {
+			server, conn := net.Pipe()
+			defer conn.Close()
+			go func() {
+				ServeStream(context.Background(), server, newMock(), WithFramer(framer))
+				server.Close()
+			}()
+
+			client := NewClient(&transport{
+				send: func(ctx context.Context, r io.Reader) error {
+					_, err := io.Copy(conn, r)
+					return err
+				},
+				recv: func(ctx context.Context) (io.ReadCloser, bool, bool, error) {
+					return ioutil.NopCloser(conn), false, false, nil
+				},
+			}, WithFramer(framer))
+
+			var result int
+			require.NoError(t, client.Do(context.Background(), "subtract", []int{5, 3}, &result))
+			require.Equal(t, 2, result)
+
+			resps, err := client.CallBatch(context.Background(), BatchRequest{
+				{Version: "2.0", Method: "sum", Params: rawMessage(`[1,2]`), ID: NewID(1)},
+				{Version: "2.0", Method: "sum", Params: rawMessage(`[3,4]`), ID: NewID(2)},
+			})
+			require.NoError(t, err)
+			resp, ok := resps.GetFromID(NewID(2))
+			require.True(t, ok)
+			require.Equal(t, "7", string(*resp.Result))
+		})
+	}
+}
+
+func rawMessage(s string) *json.RawMessage {
+	raw := json.RawMessage(s)
+	return &raw
+}
@@ -55,3 +55,13 @@ func (ints Interceptors) wrapChain(sub Interceptor) Interceptor {
 		}), 0)
 	}
 }
+
+// Safe marks handler as safe, that is, the method has no side effects and its result can be cached.
+// Safe methods can be called by HTTP GET in httpjrpc.
+func Safe(handler Handler) Handler {
+	return safeHandler{handler}
+}
+
+type safeHandler struct {
+	Handler
+}
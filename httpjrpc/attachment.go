@@ -0,0 +1,243 @@
+package httpjrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	"github.com/daichitakahashi/jrpc"
+)
+
+/*
+TODO:
+
+*/
+
+// requestPartName is the name of the part of multipart/form-data that contains JSON-RPC request.
+const requestPartName = "request"
+
+// ErrAttachmentNotFound is returned by OpenAttachment when the request has no attachment of the name.
+var ErrAttachmentNotFound = errors.New("httpjrpc: attachment not found")
+
+// WithAttachments makes Repository accept multipart/form-data, that consists of the part named "request"
+// for JSON-RPC request (single or batch), and the other parts for binary attachments.
+// Handlers open the attachments by OpenAttachment.
+// The whole request body is received before the request is executed, so that handlers can open
+// the attachments in any order. Attachments up to maxMemory in total are held in memory, and the rest
+// are stored in temporary files, which are removed after the response.
+// Request body larger than maxSize is rejected with 413, instead of the limit of WithMaxBodySize.
+func WithAttachments(maxSize, maxMemory int64) HandlerOption {
+	return handlerOptionFunc(func(opts *handlerOptions) {
+		opts.attachments = true
+		opts.maxUploadSize = maxSize
+		opts.maxMemory = maxMemory
+	})
+}
+
+type attachmentsKey struct{}
+
+// OpenAttachment opens the attachment of name, sent with the request by multipart/form-data.
+// It can be called from interceptors and handlers of the request.
+// The attachment is already received in memory or temporary file (see WithAttachments),
+// so it can be opened more than once.
+func OpenAttachment(ctx context.Context, name string) (io.ReadCloser, error) {
+	form, ok := ctx.Value(attachmentsKey{}).(*multipart.Form)
+	if !ok {
+		return nil, ErrAttachmentNotFound
+	}
+	files := form.File[name]
+	if len(files) == 0 {
+		return nil, ErrAttachmentNotFound
+	}
+	return files[0].Open()
+}
+
+// isMultipart reports whether contentType is multipart/form-data.
+func isMultipart(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+func (r *Repository) serveMultipart(w http.ResponseWriter, req *http.Request, limitWriter http.ResponseWriter) {
+	if r.options.maxUploadSize > 0 {
+		req.Body = http.MaxBytesReader(limitWriter, req.Body, r.options.maxUploadSize)
+	}
+	err := req.ParseMultipartForm(r.options.maxMemory)
+	if err != nil {
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	form := req.MultipartForm
+	defer form.RemoveAll()
+
+	var body io.Reader
+	if v := form.Value[requestPartName]; len(v) > 0 {
+		body = strings.NewReader(v[0])
+	} else if fhs := form.File[requestPartName]; len(fhs) > 0 {
+		f, err := fhs[0].Open()
+		if err != nil {
+			r.handleError(req, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		defer f.Close()
+		body = f
+	} else {
+		http.Error(w, `missing "request" part`, http.StatusBadRequest)
+		return
+	}
+
+	requests, batch, err := decodeRequests(body)
+	switch {
+	case err == io.EOF || err == errTrailingData:
+		r.writeResponses(w, req, []*jrpc.Response{parseError(err)}, false)
+	case err != nil:
+		r.handleError(req, err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	case batch && len(requests) == 0:
+		r.writeResponses(w, req, []*jrpc.Response{invalidRequest()}, false)
+	default:
+		req = req.WithContext(context.WithValue(req.Context(), attachmentsKey{}, form))
+		r.execute(w, req, requests, batch)
+	}
+}
+
+// Attachment is a binary part sent with JSON-RPC request by Transport.Upload.
+type Attachment struct {
+	Name        string // referred by OpenAttachment
+	Filename    string // optional
+	ContentType string // default is application/octet-stream
+	Body        io.Reader
+}
+
+// Upload sends req with attachments by multipart/form-data, to Repository with WithAttachments.
+// The attachments are read from Body while sending, without being held in memory on the client side.
+// If req is a notification, Upload returns nil response.
+func (ht *Transport) Upload(ctx context.Context, req *jrpc.Request, attachments ...Attachment) (*jrpc.Response, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ht.upload(ctx, data, req.ID != jrpc.NoID, attachments)
+	if err != nil || body == nil {
+		return nil, err
+	}
+
+	var res jrpc.Response
+	err = json.Unmarshal(body, &res)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// UploadBatch is like Upload, but sends batch request.
+// The attachments are shared by all requests in the batch.
+// If the batch consists of notifications only, UploadBatch returns nil response.
+func (ht *Transport) UploadBatch(ctx context.Context, reqs jrpc.BatchRequest, attachments ...Attachment) (jrpc.BatchResponse, error) {
+	data, err := json.Marshal(reqs)
+	if err != nil {
+		return nil, err
+	}
+	var hasID bool
+	for _, req := range reqs {
+		hasID = hasID || req.ID != jrpc.NoID
+	}
+	body, err := ht.upload(ctx, data, hasID, attachments)
+	if err != nil || body == nil {
+		return nil, err
+	}
+
+	var resps jrpc.BatchResponse
+	if body = bytes.TrimLeft(body, " \t\r\n"); len(body) > 0 && body[0] != '[' {
+		// error of the batch itself, such as Parse error
+		var res jrpc.Response
+		err = json.Unmarshal(body, &res)
+		return jrpc.BatchResponse{&res}, err
+	}
+	err = json.Unmarshal(body, &resps)
+	if err != nil {
+		return nil, err
+	}
+	return resps, nil
+}
+
+// upload sends request message data with attachments, and returns the response body.
+// If hasID is false, the response is discarded and nil is returned.
+func (ht *Transport) upload(ctx context.Context, data []byte, hasID bool, attachments []Attachment) ([]byte, error) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeMultipart(mw, data, attachments))
+	}()
+
+	httpReq, err := http.NewRequest(http.MethodPost, ht.url, pr)
+	if err != nil {
+		pr.Close()
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", mw.FormDataContentType())
+	resp, err := ht.send(ctx, httpReq)
+	pr.Close()
+	if err != nil {
+		return nil, err
+	}
+	defer discard(resp.Body)
+	if !hasID {
+		return nil, nil
+	} else if resp.StatusCode == http.StatusNoContent || resp.ContentLength == 0 {
+		return nil, jrpc.ErrMissingResponse
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func writeMultipart(mw *multipart.Writer, request []byte, attachments []Attachment) error {
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="`+requestPartName+`"`)
+	h.Set("Content-Type", contentTypeJSON)
+	part, err := mw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	if _, err = part.Write(request); err != nil {
+		return err
+	}
+
+	for _, a := range attachments {
+		contentType := a.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		filename := a.Filename
+		if filename == "" {
+			filename = a.Name
+		}
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{
+			"name":     a.Name,
+			"filename": filename,
+		}))
+		h.Set("Content-Type", contentType)
+		part, err := mw.CreatePart(h)
+		if err != nil {
+			return err
+		}
+		if _, err = io.Copy(part, a.Body); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
@@ -0,0 +1,190 @@
+package httpjrpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/daichitakahashi/jrpc"
+	"github.com/stretchr/testify/require"
+)
+
+func newAttachmentCore(notified chan<- string) *jrpc.Core {
+	core := newCore()
+	core.Register("digest", jrpc.HandlerFunc(func(ctx context.Context, params *json.RawMessage) (interface{}, *jrpc.Error) {
+		var names []string
+		if err := jrpc.UnmarshalParams(params, &names); err != nil {
+			return nil, err
+		}
+		digests := make([]string, len(names))
+		for i, name := range names {
+			f, err := OpenAttachment(ctx, name)
+			if err != nil {
+				return nil, &jrpc.Error{Code: -32001, Message: err.Error()}
+			}
+			h := sha256.New()
+			_, err = io.Copy(h, f)
+			f.Close()
+			if err != nil {
+				return nil, jrpc.ErrInternal(err)
+			}
+			digests[i] = hex.EncodeToString(h.Sum(nil))
+		}
+		if notified != nil {
+			notified <- strings.Join(digests, ",")
+		}
+		return digests, nil
+	}), nil, nil)
+	return core
+}
+
+func digest(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func TestTransport_Upload(t *testing.T) {
+	notified := make(chan string, 1)
+	server := httptest.NewServer(NewRepository(newAttachmentCore(notified), WithAttachments(8<<20, 1<<10)))
+	defer server.Close()
+	transport := NewTransport(server.URL)
+	ctx := context.Background()
+
+	small := []byte("hello")
+	large := bytes.Repeat([]byte("0123456789"), 200<<10) // stored in temporary file
+
+	req, err := jrpc.NewRequest("digest", []string{"small", "large"}, jrpc.NewID(1))
+	require.NoError(t, err)
+	resp, err := transport.Upload(ctx, req,
+		Attachment{Name: "small", Body: bytes.NewReader(small)},
+		Attachment{Name: "large", Filename: "large.bin", ContentType: "application/x-binary", Body: bytes.NewReader(large)},
+	)
+	require.NoError(t, err)
+	require.Nil(t, resp.Error)
+	var digests []string
+	require.NoError(t, resp.DecodeResult(&digests))
+	require.Equal(t, []string{digest(small), digest(large)}, digests)
+	<-notified
+
+	// missing attachment
+	req, err = jrpc.NewRequest("digest", []string{"missing"}, jrpc.NewID(2))
+	require.NoError(t, err)
+	resp, err = transport.Upload(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, ErrAttachmentNotFound.Error(), resp.Error.Message)
+
+	// notification
+	req, err = jrpc.NewRequest("digest", []string{"small"}, jrpc.NoID)
+	require.NoError(t, err)
+	resp, err = transport.Upload(ctx, req, Attachment{Name: "small", Body: bytes.NewReader(small)})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+	require.Equal(t, digest(small), <-notified)
+}
+
+func TestTransport_UploadBatch(t *testing.T) {
+	notified := make(chan string, 2)
+	server := httptest.NewServer(NewRepository(newAttachmentCore(notified), WithAttachments(1<<20, 1<<10)))
+	defer server.Close()
+	transport := NewTransport(server.URL)
+	ctx := context.Background()
+
+	a, b := []byte("hello"), []byte("world")
+	req1, err := jrpc.NewRequest("digest", []string{"a"}, jrpc.NewID(1))
+	require.NoError(t, err)
+	req2, err := jrpc.NewRequest("digest", []string{"a", "b"}, jrpc.NewID(2))
+	require.NoError(t, err)
+	resps, err := transport.UploadBatch(ctx, jrpc.BatchRequest{req1, req2},
+		Attachment{Name: "a", Body: bytes.NewReader(a)},
+		Attachment{Name: "b", Body: bytes.NewReader(b)},
+	)
+	require.NoError(t, err)
+	require.Len(t, resps, 2)
+	results := map[jrpc.ID][]string{}
+	for _, resp := range resps {
+		require.Nil(t, resp.Error)
+		var digests []string
+		require.NoError(t, resp.DecodeResult(&digests))
+		results[resp.ID] = digests
+	}
+	require.Equal(t, map[jrpc.ID][]string{
+		jrpc.NewID(1): {digest(a)},
+		jrpc.NewID(2): {digest(a), digest(b)},
+	}, results)
+	<-notified
+	<-notified
+
+	// notifications only
+	req, err := jrpc.NewRequest("digest", []string{"a"}, jrpc.NoID)
+	require.NoError(t, err)
+	resps, err = transport.UploadBatch(ctx, jrpc.BatchRequest{req}, Attachment{Name: "a", Body: bytes.NewReader(a)})
+	require.NoError(t, err)
+	require.Nil(t, resps)
+	require.Equal(t, digest(a), <-notified)
+}
+
+func TestTransport_Upload_NoContent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	transport := NewTransport(server.URL)
+
+	req, err := jrpc.NewRequest("digest", []string{"a"}, jrpc.NewID(1))
+	require.NoError(t, err)
+	_, err = transport.Upload(context.Background(), req, Attachment{Name: "a", Body: strings.NewReader("a")})
+	require.Equal(t, jrpc.ErrMissingResponse, err)
+
+	_, err = transport.UploadBatch(context.Background(), jrpc.BatchRequest{req}, Attachment{Name: "a", Body: strings.NewReader("a")})
+	require.Equal(t, jrpc.ErrMissingResponse, err)
+}
+
+func TestTransport_Upload_TooLarge(t *testing.T) {
+	server := httptest.NewServer(NewRepository(newAttachmentCore(nil), WithAttachments(1<<10, 1<<10)))
+	defer server.Close()
+
+	req, err := jrpc.NewRequest("digest", []string{"large"}, jrpc.NewID(1))
+	require.NoError(t, err)
+	_, err = NewTransport(server.URL).Upload(context.Background(), req,
+		Attachment{Name: "large", Body: bytes.NewReader(make([]byte, 4<<10))},
+	)
+	require.IsType(t, &StatusError{}, err)
+	require.Equal(t, http.StatusRequestEntityTooLarge, err.(*StatusError).StatusCode)
+}
+
+func TestRepository_ServeHTTP_Multipart(t *testing.T) {
+	post := func(h http.Handler, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", `multipart/form-data; boundary=xxx`)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	body := "--xxx\r\n" +
+		"Content-Disposition: form-data; name=\"file\"; filename=\"a.txt\"\r\n\r\n" +
+		"hello\r\n" +
+		"--xxx\r\n" +
+		"Content-Disposition: form-data; name=\"request\"; filename=\"request.json\"\r\n" +
+		"Content-Type: application/json\r\n\r\n" +
+		`[{"jsonrpc":"2.0","method":"digest","params":["file"],"id":1}]` + "\r\n" +
+		"--xxx--\r\n"
+
+	rec := post(NewRepository(newAttachmentCore(nil), WithAttachments(1<<20, 1<<20)), body)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `[{"jsonrpc":"2.0","result":["`+digest([]byte("hello"))+`"],"id":1}]`, rec.Body.String())
+
+	// without "request" part
+	rec = post(NewRepository(newAttachmentCore(nil), WithAttachments(1<<20, 1<<20)), "--xxx--\r\n")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// not enabled
+	rec = post(NewRepository(newAttachmentCore(nil)), body)
+	require.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+}
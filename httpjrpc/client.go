@@ -1,12 +1,16 @@
 package httpjrpc
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
 
 	"github.com/daichitakahashi/jrpc"
-	"github.com/pkg/errors"
 )
 
 /*
@@ -14,124 +18,233 @@ TODO:
 - bufferpool
 */
 
-// Transport is
-type Transport struct {
-	url    string
-	client http.Client
-	resp   *http.Response
+// StatusError is returned when the server responds with unexpected HTTP status.
+type StatusError struct {
+	StatusCode int
+	Body       []byte // truncated to maxErrorBodySize
 }
 
-// SendRequest is
-func (ht *Transport) SendRequest(ctx context.Context, r io.Reader) error {
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("httpjrpc: status code %d", e.StatusCode)
+}
 
-	req, err := http.NewRequest("POST", ht.url, r)
-	/*for _, ri := range ht.options.requestInterceptors {
-		ri(req)
-	}*/
-	req.Header.Set("Content-Type", "application/json")
-	req = req.WithContext(ctx)
+// Status implements jrpc.StatusCoder.
+func (e *StatusError) Status() int {
+	return e.StatusCode
+}
 
-	ht.resp, err = ht.client.Do(req)
-	if err != nil {
-		return err
+const maxErrorBodySize = 4 << 10
+
+func newStatusError(resp *http.Response) *StatusError {
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	discard(resp.Body)
+	return &StatusError{
+		StatusCode: resp.StatusCode,
+		Body:       body,
 	}
-	return nil
+}
 
+// Transport is
+type Transport struct {
+	url     string
+	options httpClientOptions
+	resp    *http.Response
 }
 
-// ReceivedResponse is
-func (ht *Transport) ReceivedResponse(_ context.Context) (recv io.ReadCloser, updated, shouldClose bool, err error) {
-	if ht.resp.StatusCode != http.StatusOK {
-		return nil, false, false, errors.Errorf("HTTPTransport: status code %d", ht.resp.StatusCode)
+// NewTransport is
+func NewTransport(url string, opts ...*HTTPClientOption) *Transport {
+	t := &Transport{
+		url:     url,
+		options: defaultHTTPClientOptions(),
 	}
-	if ht.resp.ContentLength == 0 {
-		return nil, false, false, nil
+	for _, opt := range opts {
+		opt.applyHTTP(&t.options)
 	}
-	return ht.resp.Body, true, true, nil
+	t.options.resolveClient()
+	return t
 }
 
-// Close is no-op
-func (ht *Transport) Close() error {
-	return nil
+// NewClient is shorthand for NewTransport and jrpc.NewClient.
+// *HTTPClientOption can be mixed with jrpc.ClientOption.
+func NewClient(url string, opts ...jrpc.ClientOption) *jrpc.Client {
+	var httpOpts []*HTTPClientOption
+	for _, opt := range opts {
+		if hco, ok := opt.(*HTTPClientOption); ok {
+			httpOpts = append(httpOpts, hco)
+		}
+	}
+	return jrpc.NewClient(NewTransport(url, httpOpts...), opts...)
 }
 
-var _ jrpc.ClientTransport = (*Transport)(nil)
-
-/*
-
-// HTTPTransport is
-type HTTPTransport struct {
-	client  *http.Client
-	url     string
-	options *httpClientOptions
+func (ht *Transport) do(ctx context.Context, r io.Reader) (*http.Response, error) {
+	req, err := ht.newRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	return ht.send(ctx, req)
 }
 
-// NewHTTPTransport is
-func NewHTTPTransport(url string, opts ...HTTPClientOption) *HTTPTransport {
-	transport := defaultHTTPTransport(url)
-	for _, opt := range opts {
-		opt.applyHTTP(transport.options)
+// send applies options to req, sends it, and checks the response.
+func (ht *Transport) send(ctx context.Context, req *http.Request) (*http.Response, error) {
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", contentTypeJSON)
+	if ht.options.encoding != "" {
+		req.Header.Set("Accept-Encoding", "gzip, deflate")
+	}
+	for _, ri := range ht.options.requestInterceptors {
+		ri(req)
 	}
-	return transport
-}
 
-// NewHTTPClient is
-func NewHTTPClient(url string, opts ...jrpc.ClientOption) *jrpc.Client {
-	transport := defaultHTTPTransport(url)
-	for _, opt := range opts {
-		if hco, ok := opt.(*HTTPClientOption); ok {
-			hco.applyHTTP(transport.options) //
+	client := ht.options.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if ht.options.encoding != "" {
+		if encoding := resp.Header.Get("Content-Encoding"); encoding != "" {
+			zr, err := newDecompressor(encoding, resp.Body)
+			if err != nil {
+				discard(resp.Body)
+				return nil, err
+			}
+			resp.Body = &decompressedBody{ReadCloser: zr, body: resp.Body}
+			resp.Header.Del("Content-Encoding")
+			resp.ContentLength = -1
 		}
 	}
-	return jrpc.NewClient(transport, opts...)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return nil, newStatusError(resp)
+	}
+	return resp, nil
 }
 
-func defaultHTTPTransport(url string) *HTTPTransport {
-	transport := &HTTPTransport{
-		client: http.DefaultClient,
-		url:    url,
+func (ht *Transport) newRequest(r io.Reader) (*http.Request, error) {
+	if ht.options.getEligible == nil && ht.options.encoding == "" {
+		return newPostRequest(ht.url, r, "")
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if ht.options.getEligible != nil {
+		if req, err := ht.newGetRequest(data); req != nil || err != nil {
+			return req, err
+		}
+	}
+	if ht.options.encoding == "" || len(data) < ht.options.compressThreshold {
+		return newPostRequest(ht.url, bytes.NewReader(data), "")
 	}
-	transport.options = defaultHTTPClientOptions(&transport.client)
-	return transport
+	data, err = compress(ht.options.encoding, data)
+	if err != nil {
+		return nil, err
+	}
+	return newPostRequest(ht.url, bytes.NewReader(data), ht.options.encoding)
 }
 
-// Transport is
-func (ht *HTTPTransport) Transport(ctx context.Context, t jrpc.Transmitter) error {
-	buf := jrpc.Pool{}.Get() // pseudo
-	defer buf.Free()
+// newGetRequest returns GET request if data is a single request eligible for GET, otherwise nil.
+func (ht *Transport) newGetRequest(data []byte) (*http.Request, error) {
+	msg := bytes.TrimLeft(data, " \t\r\n")
+	if len(msg) == 0 || msg[0] != '{' {
+		return nil, nil
+	}
+	var request jrpc.Request
+	if json.Unmarshal(msg, &request) != nil || request.ID == jrpc.NoID || !ht.options.getEligible(&request) {
+		return nil, nil
+	}
 
-	r, err := t.Transmit(ctx, buf)
+	u, err := url.Parse(ht.url)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	req, err := http.NewRequest("POST", ht.url, buf)
-	for _, ri := range ht.options.requestInterceptors {
-		ri(req)
+	query := u.Query()
+	query.Set("method", request.Method)
+	if request.Params != nil {
+		query.Set("params", string(*request.Params))
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.ContentLength = int64(buf.Len())
-	req = req.WithContext(ctx)
+	id, err := json.Marshal(request.ID)
+	if err != nil {
+		return nil, err
+	}
+	query.Set("id", string(id))
+	u.RawQuery = query.Encode()
+	return http.NewRequest(http.MethodGet, u.String(), nil)
+}
 
-	resp, err := ht.client.Do(req)
+func newPostRequest(target string, r io.Reader, encoding string) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodPost, target, r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentTypeJSON)
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	return req, nil
+}
+
+// SendRequest is
+func (ht *Transport) SendRequest(ctx context.Context, r io.Reader) error {
+	if ht.resp != nil {
+		discard(ht.resp.Body)
+		ht.resp = nil
+	}
+	resp, err := ht.do(ctx, r)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		io.Copy(ioutil.Discard, resp.Body)
-		resp.Body.Close()
-	}()
+	ht.resp = resp
+	return nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return errors.New("HTTPTransport: status code ") // =============================================
+// ReceivedResponse is
+// It returns nil if the server returns no content. Client reports it as jrpc.ErrMissingResponse
+// when the request is not a notification.
+func (ht *Transport) ReceivedResponse(_ context.Context) (recv io.ReadCloser, updated, shouldClose bool, err error) {
+	resp := ht.resp
+	ht.resp = nil
+	if resp == nil {
+		return nil, false, false, nil
+	}
+	if resp.StatusCode == http.StatusNoContent || resp.ContentLength == 0 {
+		discard(resp.Body)
+		return nil, false, false, nil
 	}
+	return resp.Body, true, true, nil
+}
 
-	err = r.Receive(ctx, resp.Body)
+// RoundTrip implements jrpc.CallTransport.
+// Each call is sent by its own HTTP request, so it can be used concurrently.
+// If the server returns no content to the request that has ids, it returns jrpc.ErrMissingResponse.
+func (ht *Transport) RoundTrip(ctx context.Context, r io.Reader, ids []jrpc.ID) (io.ReadCloser, error) {
+	resp, err := ht.do(ctx, r)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if len(ids) == 0 {
+		discard(resp.Body)
+		return nil, nil
+	} else if resp.StatusCode == http.StatusNoContent || resp.ContentLength == 0 {
+		discard(resp.Body)
+		return nil, jrpc.ErrMissingResponse
 	}
+	return resp.Body, nil
+}
+
+// Close is no-op
+func (ht *Transport) Close() error {
 	return nil
 }
 
-var _ jrpc.ClientTransport = (*HTTPTransport)(nil)
+// discard reads rest of body for reuse of the connection, and closes it.
+func discard(body io.ReadCloser) {
+	io.Copy(ioutil.Discard, body)
+	body.Close()
+}
 
-*/
+var (
+	_ jrpc.CallTransport = (*Transport)(nil)
+	_ jrpc.StatusCoder   = (*StatusError)(nil)
+)
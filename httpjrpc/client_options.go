@@ -14,7 +14,11 @@ TODO:
 type (
 	httpClientOptions struct {
 		requestInterceptors []func(*http.Request)
-		client              **http.Client
+		client              *http.Client
+		roundTripper        http.RoundTripper
+		getEligible         func(req *jrpc.Request) bool
+		encoding            string
+		compressThreshold   int
 	}
 
 	// HTTPClientOption is implemented jtpc.ClientOption but do nothing
@@ -24,10 +28,23 @@ type (
 	}
 )
 
-func defaultHTTPClientOptions(clientPt **http.Client) *httpClientOptions {
-	return &httpClientOptions{
+func defaultHTTPClientOptions() httpClientOptions {
+	return httpClientOptions{
 		requestInterceptors: []func(*http.Request){},
-		client:              clientPt,
+		client:              http.DefaultClient,
+	}
+}
+
+// resolveClient builds the client from WithHTTPClient and WithRoundTripper.
+// It is called after all options are applied.
+func (opts *httpClientOptions) resolveClient() {
+	if opts.client == nil {
+		opts.client = http.DefaultClient
+	}
+	if opts.roundTripper != nil {
+		hc := *opts.client
+		hc.Transport = opts.roundTripper
+		opts.client = &hc
 	}
 }
 
@@ -44,30 +61,71 @@ func WithBasicAuth(user, password string) *HTTPClientOption {
 
 // WithUserAgent is
 func WithUserAgent(ua string) *HTTPClientOption {
+	return WithHeader("User-Agent", ua)
+}
+
+// WithHeader sets header to every HTTP request.
+func WithHeader(key, value string) *HTTPClientOption {
 	return &HTTPClientOption{
 		applyHTTP: func(opts *httpClientOptions) {
 			opts.requestInterceptors = append(opts.requestInterceptors, func(r *http.Request) {
-				r.Header.Set("User-Agent", ua)
+				r.Header.Set(key, value)
 			})
 		},
 	}
 }
 
+// WithRequestHook is
+// fn is called with each HTTP request before sending, so that headers can be set per request,
+// for example from the values of the request's context.
+func WithRequestHook(fn func(r *http.Request)) *HTTPClientOption {
+	return &HTTPClientOption{
+		applyHTTP: func(opts *httpClientOptions) {
+			opts.requestInterceptors = append(opts.requestInterceptors, fn)
+		},
+	}
+}
+
 // WithHTTPClient is
-// proof: https://play.golang.org/p/RCLHTp-qY80
+// Default is http.DefaultClient.
 func WithHTTPClient(hc *http.Client) *HTTPClientOption {
 	return &HTTPClientOption{
 		applyHTTP: func(opts *httpClientOptions) {
-			*opts.client = hc
+			opts.client = hc
 		},
 	}
 }
 
 // WithRoundTripper option
+// The http.Client given by WithHTTPClient is copied, not modified, regardless of the order of options.
 func WithRoundTripper(rt http.RoundTripper) *HTTPClientOption {
 	return &HTTPClientOption{
 		applyHTTP: func(opts *httpClientOptions) {
-			(*(opts.client)).Transport = rt
+			opts.roundTripper = rt
+		},
+	}
+}
+
+// WithGET makes Transport send single request as HTTP GET when eligible reports true,
+// so that the response can be cached by CDNs and browsers.
+// jrpc.IdempotentMethods can be used. The methods must be registered with jrpc.Safe on the server.
+// Batch requests and notifications are always sent as POST.
+func WithGET(eligible func(req *jrpc.Request) bool) *HTTPClientOption {
+	return &HTTPClientOption{
+		applyHTTP: func(opts *httpClientOptions) {
+			opts.getEligible = eligible
+		},
+	}
+}
+
+// WithCompression makes Transport compress request body of threshold bytes or larger by encoding,
+// which is "gzip" or "deflate".
+// Transport also requests compressed response by Accept-Encoding, and decompresses it transparently.
+func WithCompression(encoding string, threshold int) *HTTPClientOption {
+	return &HTTPClientOption{
+		applyHTTP: func(opts *httpClientOptions) {
+			opts.encoding = encoding
+			opts.compressThreshold = threshold
 		},
 	}
 }
@@ -0,0 +1,202 @@
+package httpjrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/daichitakahashi/jrpc"
+	"github.com/stretchr/testify/require"
+)
+
+func newCore() *jrpc.Core {
+	core := jrpc.NewRepository()
+	core.Register("echo", jrpc.HandlerFunc(func(_ context.Context, params *json.RawMessage) (interface{}, *jrpc.Error) {
+		var v interface{}
+		if err := jrpc.UnmarshalParams(params, &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}), nil, nil)
+	return core
+}
+
+func TestTransport_Go(t *testing.T) {
+	server := httptest.NewServer(&Repository{Core: newCore()})
+	defer server.Close()
+
+	client := jrpc.NewClient(&Transport{url: server.URL})
+	ctx := context.Background()
+
+	calls := make([]*jrpc.Call, 20)
+	for i := range calls {
+		calls[i] = client.Go(ctx, "echo", []int{i})
+	}
+	for i, call := range calls {
+		var result []int
+		require.NoError(t, call.Result(&result))
+		require.Equal(t, []int{i}, result)
+	}
+	require.NoError(t, client.Notify(ctx, "echo", nil))
+}
+
+func TestNewClient(t *testing.T) {
+	type ctxKey struct{}
+	var header http.Header
+	handler := NewRepository(newCore())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL,
+		WithBasicAuth("user", "password"),
+		WithUserAgent("jrpc-test"),
+		WithHeader("X-Api-Key", "secret"),
+		WithRequestHook(func(r *http.Request) {
+			if v, ok := r.Context().Value(ctxKey{}).(string); ok {
+				r.Header.Set("X-Request-Id", v)
+			}
+		}),
+		WithHTTPClient(server.Client()),
+	)
+	ctx := context.WithValue(context.Background(), ctxKey{}, "req-1")
+
+	var result []int
+	require.NoError(t, client.Do(ctx, "echo", []int{1}, &result))
+	require.Equal(t, []int{1}, result)
+
+	user, password, ok := (&http.Request{Header: header}).BasicAuth()
+	require.True(t, ok)
+	require.Equal(t, "user", user)
+	require.Equal(t, "password", password)
+	require.Equal(t, "jrpc-test", header.Get("User-Agent"))
+	require.Equal(t, "secret", header.Get("X-Api-Key"))
+	require.Equal(t, "req-1", header.Get("X-Request-Id"))
+	require.Equal(t, "application/json", header.Get("Content-Type"))
+}
+
+// countingRoundTripper counts HTTP requests sent through it.
+type countingRoundTripper struct {
+	n int32
+}
+
+func (rt *countingRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&rt.n, 1)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestNewClient_WithRoundTripper(t *testing.T) {
+	server := httptest.NewServer(NewRepository(newCore()))
+	defer server.Close()
+	ctx := context.Background()
+
+	// regardless of the order of options, and with nil http.Client
+	for _, hc := range []*http.Client{nil, {}} {
+		for _, after := range []bool{false, true} {
+			rt := &countingRoundTripper{}
+			opts := []jrpc.ClientOption{WithHTTPClient(hc), WithRoundTripper(rt)}
+			if after {
+				opts[0], opts[1] = opts[1], opts[0]
+			}
+			var result []int
+			require.NoError(t, NewClient(server.URL, opts...).Do(ctx, "echo", []int{1}, &result))
+			require.Equal(t, int32(1), atomic.LoadInt32(&rt.n))
+		}
+	}
+}
+
+func TestTransport_StatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	err := client.Do(context.Background(), "echo", nil, nil)
+	var se *StatusError
+	require.True(t, errors.As(err, &se))
+	require.Equal(t, http.StatusServiceUnavailable, se.StatusCode)
+	require.Equal(t, "service unavailable\n", string(se.Body))
+	require.EqualError(t, err, "httpjrpc: status code 503")
+}
+
+func TestTransport_NoContent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	// call without response never succeeds
+	client := NewClient(server.URL)
+	var result []int
+	require.Equal(t, jrpc.ErrMissingResponse, client.Do(ctx, "echo", []int{1}, &result))
+	require.Equal(t, jrpc.ErrMissingResponse, client.Go(ctx, "echo", []int{1}).Err())
+	require.NoError(t, client.Notify(ctx, "echo", nil))
+
+	// through SendRequest and ReceivedResponse
+	client = jrpc.NewClient(struct{ jrpc.ClientTransport }{NewTransport(server.URL)})
+	require.Equal(t, jrpc.ErrMissingResponse, client.Do(ctx, "echo", []int{1}, &result))
+	require.NoError(t, client.Notify(ctx, "echo", nil))
+}
+
+func TestNewClient_WithGET(t *testing.T) {
+	core := newCore()
+	core.Register("get", jrpc.Safe(jrpc.HandlerFunc(func(_ context.Context, params *json.RawMessage) (interface{}, *jrpc.Error) {
+		return "got", nil
+	})), nil, nil)
+	handler := NewRepository(core)
+
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, WithGET(jrpc.IdempotentMethods("get")))
+	ctx := context.Background()
+
+	var result string
+	require.NoError(t, client.Do(ctx, "get", []int{1}, &result))
+	require.Equal(t, "got", result)
+
+	var echo []int
+	require.NoError(t, client.Do(ctx, "echo", []int{1}, &echo))
+	require.Equal(t, []int{1}, echo)
+
+	require.NoError(t, client.Notify(ctx, "get", nil))
+	require.Equal(t, []string{http.MethodGet, http.MethodPost, http.MethodPost}, methods)
+}
+
+func TestNewClient_WithCompression(t *testing.T) {
+	handler := NewRepository(newCore(), WithCompressionThreshold(64))
+	var requestEncodings, responseEncodings []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestEncodings = append(requestEncodings, r.Header.Get("Content-Encoding"))
+		handler.ServeHTTP(w, r)
+		responseEncodings = append(responseEncodings, w.Header().Get("Content-Encoding"))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, WithCompression("gzip", 150))
+	ctx := context.Background()
+
+	var result []string
+	require.NoError(t, client.Do(ctx, "echo", []string{"a"}, &result))
+	require.Equal(t, []string{"a"}, result)
+
+	long := strings.Repeat("a", 200)
+	require.NoError(t, client.Do(ctx, "echo", []string{long}, &result))
+	require.Equal(t, []string{long}, result)
+
+	require.Equal(t, []string{"", "gzip"}, requestEncodings)
+	require.Equal(t, []string{"", "gzip"}, responseEncodings)
+}
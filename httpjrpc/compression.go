@@ -0,0 +1,105 @@
+package httpjrpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Content codings supported by Repository and Transport.
+// "deflate" is zlib format as defined in RFC 9110.
+const (
+	encodingGzip    = "gzip"
+	encodingDeflate = "deflate"
+)
+
+// errUnsupportedEncoding is returned for content coding other than gzip and deflate.
+var errUnsupportedEncoding = errors.New("httpjrpc: unsupported content encoding")
+
+// newDecompressor returns reader that decompresses r encoded by encoding.
+func newDecompressor(encoding string, r io.Reader) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case encodingGzip, "x-gzip":
+		return gzip.NewReader(r)
+	case encodingDeflate:
+		return zlib.NewReader(r)
+	default:
+		return nil, errors.Wrap(errUnsupportedEncoding, encoding)
+	}
+}
+
+// compress encodes data by encoding.
+func compress(encoding string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case encodingGzip:
+		w = gzip.NewWriter(&buf)
+	case encodingDeflate:
+		w = zlib.NewWriter(&buf)
+	default:
+		return nil, errors.Wrap(errUnsupportedEncoding, encoding)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// negotiateEncoding selects content coding from Accept-Encoding header values.
+// gzip is preferred to deflate when both are acceptable. It returns "" for identity.
+func negotiateEncoding(values []string) string {
+	// the wildcard applies only to the codings not listed explicitly (RFC 9110 12.5.3)
+	var gzipListed, gzipOK, deflateListed, deflateOK, anyOK bool
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			coding, ok := part, true
+			if i := strings.IndexByte(part, ';'); i >= 0 {
+				coding, ok = part[:i], !rejected(part[i+1:])
+			}
+			switch strings.ToLower(strings.TrimSpace(coding)) {
+			case encodingGzip, "x-gzip":
+				gzipListed, gzipOK = true, gzipOK || ok
+			case encodingDeflate:
+				deflateListed, deflateOK = true, deflateOK || ok
+			case "*":
+				anyOK = anyOK || ok
+			}
+		}
+	}
+	if gzipOK || (anyOK && !gzipListed) {
+		return encodingGzip
+	} else if deflateOK || (anyOK && !deflateListed) {
+		return encodingDeflate
+	}
+	return ""
+}
+
+// rejected reports whether params of Accept-Encoding element has "q=0".
+func rejected(params string) bool {
+	params = strings.TrimSpace(params)
+	if !strings.HasPrefix(params, "q=") {
+		return false
+	}
+	q, err := strconv.ParseFloat(params[2:], 64)
+	return err == nil && q == 0
+}
+
+// decompressedBody closes both of decompressor and the original body.
+type decompressedBody struct {
+	io.ReadCloser
+	body io.Closer
+}
+
+func (db *decompressedBody) Close() error {
+	db.ReadCloser.Close()
+	return db.body.Close()
+}
@@ -0,0 +1,103 @@
+package httpjrpc
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+/*
+TODO:
+
+*/
+
+// WithHeaderValue maps HTTP request header to context value of key, such as "Authorization" and "X-Tenant-Id".
+// Interceptors and handlers get the header value by ctx.Value(key).(string).
+// If the header is absent, the value is not set.
+func WithHeaderValue(header string, key interface{}) HandlerOption {
+	return handlerOptionFunc(func(opts *handlerOptions) {
+		opts.requestContext = append(opts.requestContext, func(ctx context.Context, r *http.Request) context.Context {
+			if v := r.Header.Get(header); v != "" {
+				return context.WithValue(ctx, key, v)
+			}
+			return ctx
+		})
+	})
+}
+
+// WithRequestContext is
+// fn modifies the context passed to interceptors and handlers, for example,
+// to parse "traceparent" header into typed value. It is derived from the context of r.
+func WithRequestContext(fn func(ctx context.Context, r *http.Request) context.Context) HandlerOption {
+	return handlerOptionFunc(func(opts *handlerOptions) {
+		opts.requestContext = append(opts.requestContext, fn)
+	})
+}
+
+type responseHeaderKey struct{}
+
+// responseHeader holds headers set by handlers until the response is written.
+// Handlers in a batch may set them concurrently.
+type responseHeader struct {
+	mu     sync.Mutex
+	header http.Header
+}
+
+// SetResponseHeader sets HTTP response header from interceptors and handlers, such as "Retry-After".
+// It reports false if ctx is not given by Repository.
+// Headers set after the response begins to be written, e.g. in a streaming batch, are ignored.
+func SetResponseHeader(ctx context.Context, key, value string) bool {
+	rh, ok := ctx.Value(responseHeaderKey{}).(*responseHeader)
+	if !ok {
+		return false
+	}
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+	rh.header.Set(key, value)
+	return true
+}
+
+func (r *Repository) requestContext(req *http.Request) (context.Context, *responseHeader) {
+	ctx := req.Context()
+	for _, fn := range r.options.requestContext {
+		ctx = fn(ctx, req)
+	}
+	rh := &responseHeader{
+		header: make(http.Header),
+	}
+	return context.WithValue(ctx, responseHeaderKey{}, rh), rh
+}
+
+// headerWriter copies headers set by handlers to the response before writing the status.
+type headerWriter struct {
+	http.ResponseWriter
+	header      *responseHeader
+	wroteHeader bool
+}
+
+func (hw *headerWriter) WriteHeader(code int) {
+	if !hw.wroteHeader {
+		hw.wroteHeader = true
+		hw.header.mu.Lock()
+		h := hw.Header()
+		for key, values := range hw.header.header {
+			h[key] = values
+		}
+		hw.header.mu.Unlock()
+	}
+	hw.ResponseWriter.WriteHeader(code)
+}
+
+func (hw *headerWriter) Write(p []byte) (int, error) {
+	if !hw.wroteHeader {
+		hw.WriteHeader(http.StatusOK)
+	}
+	return hw.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher.
+func (hw *headerWriter) Flush() {
+	if f, ok := hw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
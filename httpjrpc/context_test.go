@@ -0,0 +1,81 @@
+package httpjrpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/daichitakahashi/jrpc"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	tenantKey  struct{}
+	traceKey   struct{}
+	traceParts []string
+)
+
+func TestRepository_ServeHTTP_Context(t *testing.T) {
+	core := jrpc.NewRepository()
+	core.With(func(ctx context.Context, params *json.RawMessage, info *jrpc.RequestInfo, handler jrpc.Handler) (interface{}, *jrpc.Error) {
+		if _, ok := ctx.Value(tenantKey{}).(string); !ok {
+			return nil, &jrpc.Error{Code: -32001, Message: "no tenant"}
+		}
+		return handler.ServeJSONRPC(ctx, params)
+	})
+	core.Register("whoami", jrpc.HandlerFunc(func(ctx context.Context, _ *json.RawMessage) (interface{}, *jrpc.Error) {
+		return []interface{}{ctx.Value(tenantKey{}), ctx.Value(traceKey{})}, nil
+	}), nil, nil)
+	core.Register("busy", jrpc.HandlerFunc(func(ctx context.Context, params *json.RawMessage) (interface{}, *jrpc.Error) {
+		var n int
+		if err := jrpc.UnmarshalParams(params, &n); err != nil {
+			return nil, err
+		}
+		SetResponseHeader(ctx, "Retry-After", strconv.Itoa(n))
+		return nil, &jrpc.Error{Code: -32002, Message: "busy"}
+	}), nil, nil)
+
+	h := NewRepository(core,
+		WithHeaderValue("X-Tenant-Id", tenantKey{}),
+		WithRequestContext(func(ctx context.Context, r *http.Request) context.Context {
+			if v := r.Header.Get("traceparent"); v != "" {
+				return context.WithValue(ctx, traceKey{}, traceParts(strings.Split(v, "-")))
+			}
+			return ctx
+		}),
+	)
+
+	post := func(body string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post(`{"jsonrpc":"2.0","method":"whoami","id":1}`, map[string]string{
+		"X-Tenant-Id": "acme",
+		"traceparent": "00-trace-span-01",
+	})
+	require.JSONEq(t, `{"jsonrpc":"2.0","result":["acme",["00","trace","span","01"]],"id":1}`, rec.Body.String())
+
+	rec = post(`{"jsonrpc":"2.0","method":"whoami","id":1}`, nil)
+	require.JSONEq(t, `{"jsonrpc":"2.0","error":{"code":-32001,"message":"no tenant"},"id":1}`, rec.Body.String())
+
+	// response headers set concurrently in a batch
+	rec = post(`[{"jsonrpc":"2.0","method":"busy","params":30,"id":1},{"jsonrpc":"2.0","method":"busy","params":30,"id":2}]`, map[string]string{
+		"X-Tenant-Id": "acme",
+	})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "30", rec.Header().Get("Retry-After"))
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	require.False(t, SetResponseHeader(context.Background(), "Retry-After", "1"))
+}
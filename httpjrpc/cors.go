@@ -0,0 +1,210 @@
+package httpjrpc
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+TODO:
+
+*/
+
+// CORS is configuration of Cross-Origin Resource Sharing.
+// It is installed to Repository by WithCORS, or used as middleware of Mux by Handler.
+type CORS struct {
+	// AllowedOrigins is a list of origins such as "https://example.com".
+	// "*" allows any origin, and "https://*.example.com" allows its subdomains.
+	AllowedOrigins []string
+	// AllowOrigin reports whether origin is allowed, in addition to AllowedOrigins.
+	// With AllowCredentials, it must not allow arbitrary origins, because the origin is echoed back.
+	AllowOrigin func(origin string) bool
+	// AllowedHeaders is a list of request headers that can be used.
+	// If it is empty, DefaultCORSHeaders is used.
+	AllowedHeaders []string
+	// ExposedHeaders is a list of response headers that can be read by the browser.
+	ExposedHeaders []string
+	// AllowCredentials allows cookies and HTTP authentication.
+	// It cannot be used with AllowedOrigins that matches any host, such as "*" and "https://*",
+	// because any site could read the responses.
+	AllowCredentials bool
+	// MaxAge is how long the result of preflight request can be cached.
+	MaxAge time.Duration
+}
+
+// DefaultCORSHeaders is the request headers allowed when CORS.AllowedHeaders is empty.
+var DefaultCORSHeaders = []string{"Accept", "Content-Type", "Content-Encoding", "Last-Event-ID"}
+
+// corsMethods is the HTTP methods served by Repository and EventStream.
+const corsMethods = "GET, POST"
+
+// WithCORS is
+// Preflight requests are answered by Repository, and CORS headers are added to all responses,
+// including errors.
+// It panics if cors allows credentials for any origin.
+func WithCORS(cors CORS) HandlerOption {
+	cors.validate()
+	return handlerOptionFunc(func(opts *handlerOptions) {
+		opts.cors = &cors
+	})
+}
+
+// Handler returns middleware that applies c to next.
+// It panics if c allows credentials for any origin.
+func (c CORS) Handler(next http.Handler) http.Handler {
+	c.validate()
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if c.apply(w, req) {
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// validate panics if c allows credentials for any origin.
+func (c *CORS) validate() {
+	if !c.AllowCredentials {
+		return
+	}
+	for _, o := range c.AllowedOrigins {
+		if anyHost(o) {
+			panic(errors.New(`httpjrpc: CORS cannot allow credentials for any origin "` + o + `"`))
+		}
+	}
+}
+
+// anyHost reports whether pattern of origin has no host part other than a wildcard.
+func anyHost(pattern string) bool {
+	if !strings.Contains(pattern, "*") {
+		return false
+	}
+	host := pattern
+	if i := strings.Index(pattern, "://"); i >= 0 {
+		host = pattern[i+3:]
+	}
+	return strings.Trim(host, "*.") == ""
+}
+
+// apply sets CORS headers, and reports whether req is preflight request and is already answered.
+func (c *CORS) apply(w http.ResponseWriter, req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	preflight := req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != ""
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	if preflight {
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+	}
+	if origin == "" {
+		return false
+	}
+	if !c.allowed(origin) {
+		if preflight {
+			w.WriteHeader(http.StatusForbidden)
+		}
+		return preflight
+	}
+
+	if preflight {
+		method := req.Header.Get("Access-Control-Request-Method")
+		if method != http.MethodGet && method != http.MethodPost {
+			w.WriteHeader(http.StatusForbidden)
+			return true
+		}
+		requested := req.Header.Get("Access-Control-Request-Headers")
+		if !c.headersAllowed(requested) {
+			w.WriteHeader(http.StatusForbidden)
+			return true
+		}
+	}
+
+	c.setOrigin(h, origin)
+	if c.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if !preflight {
+		if len(c.ExposedHeaders) > 0 {
+			h.Set("Access-Control-Expose-Headers", strings.Join(c.ExposedHeaders, ", "))
+		}
+		return false
+	}
+
+	h.Set("Access-Control-Allow-Methods", corsMethods)
+	if requested := req.Header.Get("Access-Control-Request-Headers"); requested != "" {
+		h.Set("Access-Control-Allow-Headers", requested)
+	}
+	if c.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge/time.Second)))
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+func (c *CORS) setOrigin(h http.Header, origin string) {
+	if !c.AllowCredentials {
+		for _, o := range c.AllowedOrigins {
+			if o == "*" {
+				h.Set("Access-Control-Allow-Origin", "*")
+				return
+			}
+		}
+	}
+	// "*" cannot be used with credentials
+	h.Set("Access-Control-Allow-Origin", origin)
+}
+
+func (c *CORS) allowed(origin string) bool {
+	for _, o := range c.AllowedOrigins {
+		if matchOrigin(o, origin) {
+			return true
+		}
+	}
+	return c.AllowOrigin != nil && c.AllowOrigin(origin)
+}
+
+// matchOrigin matches origin with pattern that can contain a wildcard.
+// The wildcard matches one or more characters except "/" and ":", unless pattern is "*" that matches any origin.
+func matchOrigin(pattern, origin string) bool {
+	if pattern == "*" {
+		return true
+	}
+	i := strings.IndexByte(pattern, '*')
+	if i < 0 {
+		return strings.EqualFold(pattern, origin)
+	}
+	prefix, suffix := strings.ToLower(pattern[:i]), strings.ToLower(pattern[i+1:])
+	origin = strings.ToLower(origin)
+	if len(origin) <= len(prefix)+len(suffix) ||
+		!strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+		return false
+	}
+	return !strings.ContainsAny(origin[len(prefix):len(origin)-len(suffix)], "/:")
+}
+
+// headersAllowed reports whether all of comma separated headers are allowed.
+func (c *CORS) headersAllowed(requested string) bool {
+	allowed := c.AllowedHeaders
+	if len(allowed) == 0 {
+		allowed = DefaultCORSHeaders
+	}
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		ok := false
+		for _, a := range allowed {
+			if a == "*" || strings.EqualFold(a, header) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,136 @@
+package httpjrpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORS(t *testing.T) {
+	h := NewRepository(newCore(), WithCORS(CORS{
+		AllowedOrigins: []string{"https://app.example.com", "https://*.example.org"},
+		AllowOrigin: func(origin string) bool {
+			return origin == "http://localhost:3000"
+		},
+		ExposedHeaders:   []string{"X-Request-Id"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}))
+
+	preflight := func(origin, method, headers string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "/", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		if headers != "" {
+			req.Header.Set("Access-Control-Request-Headers", headers)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("preflight", func(t *testing.T) {
+		for _, origin := range []string{"https://app.example.com", "https://api.example.org", "http://localhost:3000"} {
+			rec := preflight(origin, http.MethodPost, "content-type")
+			require.Equal(t, http.StatusNoContent, rec.Code)
+			require.Equal(t, origin, rec.Header().Get("Access-Control-Allow-Origin"))
+			require.Equal(t, "GET, POST", rec.Header().Get("Access-Control-Allow-Methods"))
+			require.Equal(t, "content-type", rec.Header().Get("Access-Control-Allow-Headers"))
+			require.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+			require.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+			require.Contains(t, rec.Header().Values("Vary"), "Origin")
+		}
+	})
+
+	t.Run("rejected preflight", func(t *testing.T) {
+		for _, rec := range []*httptest.ResponseRecorder{
+			preflight("https://evil.example.com", http.MethodPost, ""),
+			preflight("https://example.org", http.MethodPost, ""),
+			preflight("https://app.example.com", http.MethodDelete, ""),
+			preflight("https://app.example.com", http.MethodPost, "Content-Type, X-Unknown"),
+		} {
+			require.Equal(t, http.StatusForbidden, rec.Code)
+			require.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+		}
+	})
+
+	t.Run("actual request", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc":"2.0","method":"unknown","id":1}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Origin", "https://app.example.com")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.JSONEq(t, `{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":1}`, rec.Body.String())
+		require.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+		require.Equal(t, "X-Request-Id", rec.Header().Get("Access-Control-Expose-Headers"))
+	})
+
+	t.Run("HTTP error", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "text/plain")
+		req.Header.Set("Origin", "https://app.example.com")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+		require.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("OPTIONS without preflight", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodOptions, "/", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	})
+}
+
+func TestCORS_Handler(t *testing.T) {
+	mux := NewMux()
+	mux.Mount("/rpc", newCore())
+	mux.Use(CORS{AllowedOrigins: []string{"*"}}.Handler)
+
+	req := httptest.NewRequest(http.MethodOptions, "/rpc", nil)
+	req.Header.Set("Origin", "https://any.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "Content-Type")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+	require.Empty(t, rec.Header().Get("Access-Control-Allow-Credentials"))
+
+	// request without Origin is not affected
+	req = httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(`{"jsonrpc":"2.0","method":"echo","params":[1],"id":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORS_AnyOriginWithCredentials(t *testing.T) {
+	for _, pattern := range []string{"*", "https://*", "*://*", "http://*.*"} {
+		cors := CORS{AllowedOrigins: []string{"https://app.example.com", pattern}, AllowCredentials: true}
+		require.Panics(t, func() { WithCORS(cors) }, pattern)
+		require.Panics(t, func() { cors.Handler(http.NotFoundHandler()) }, pattern)
+	}
+
+	// explicit origins and subdomain patterns can be used with credentials
+	cors := CORS{AllowedOrigins: []string{"https://app.example.com", "https://*.example.com"}, AllowCredentials: true}
+	require.NotPanics(t, func() { WithCORS(cors) })
+}
+
+func TestMatchOrigin(t *testing.T) {
+	require.True(t, matchOrigin("https://*.example.com", "https://api.example.com"))
+	require.True(t, matchOrigin("https://*.example.com", "https://a.b.example.com"))
+	require.True(t, matchOrigin("*", "https://example.com:8080"))
+	require.False(t, matchOrigin("https://*.example.com", "https://.example.com"))
+	require.False(t, matchOrigin("https://*.example.com", "https://evil.com/.example.com"))
+	require.False(t, matchOrigin("https://*.example.com", "https://evil.com:1.example.com"))
+	require.False(t, matchOrigin("https://*.example.com", "https://example.com"))
+}
@@ -0,0 +1,284 @@
+package httpjrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+/*
+TODO:
+
+*/
+
+// ErrEventStreamClosed is returned by Publish after EventStream is closed.
+var ErrEventStreamClosed = errors.New("httpjrpc: EventStream closed")
+
+type (
+	eventStreamOptions struct {
+		replaySize  int
+		bufferSize  int
+		heartbeat   time.Duration
+		clientRetry time.Duration
+	}
+
+	// EventStreamOption is
+	EventStreamOption interface {
+		applyEventStream(opts *eventStreamOptions)
+	}
+
+	eventStreamOptionFunc func(opts *eventStreamOptions)
+)
+
+func (esof eventStreamOptionFunc) applyEventStream(opts *eventStreamOptions) {
+	esof(opts)
+}
+
+var defaultEventStreamOptions = eventStreamOptions{
+	replaySize: 256,
+	bufferSize: 64,
+	heartbeat:  30 * time.Second,
+}
+
+// WithReplayBuffer is
+// The last n events are kept, and sent again to the client that reconnects with Last-Event-ID.
+// Default is 256. If n <= 0, events are not replayed.
+func WithReplayBuffer(n int) EventStreamOption {
+	return eventStreamOptionFunc(func(opts *eventStreamOptions) {
+		opts.replaySize = n
+	})
+}
+
+// WithSubscriberBuffer is
+// When a subscriber falls behind by more than n events, its connection is closed,
+// so that it reconnects and catches up from the replay buffer. Default is 64.
+func WithSubscriberBuffer(n int) EventStreamOption {
+	return eventStreamOptionFunc(func(opts *eventStreamOptions) {
+		opts.bufferSize = n
+	})
+}
+
+// WithHeartbeat is
+// Comment line is sent every d to keep the connection through proxies. Default is 30 seconds.
+// If d <= 0, heartbeat is disabled.
+func WithHeartbeat(d time.Duration) EventStreamOption {
+	return eventStreamOptionFunc(func(opts *eventStreamOptions) {
+		opts.heartbeat = d
+	})
+}
+
+// WithClientRetry is
+// The client is told to wait d before reconnecting by "retry" field.
+// By default, the field is not sent and the client decides.
+func WithClientRetry(d time.Duration) EventStreamOption {
+	return eventStreamOptionFunc(func(opts *eventStreamOptions) {
+		opts.clientRetry = d
+	})
+}
+
+type (
+	// EventStream is http.Handler that pushes JSON-RPC notifications to clients by Server-Sent Events.
+	// Each notification is sent as "data" of an event that has sequential "id".
+	// Handlers of jrpc.Core can publish notifications by holding EventStream.
+	EventStream struct {
+		options eventStreamOptions
+
+		mu          sync.Mutex
+		lastID      uint64
+		replay      []event // ring buffer
+		head        int
+		subscribers map[*subscriber]struct{}
+		closed      bool
+		done        chan struct{}
+	}
+
+	event struct {
+		id   uint64
+		data []byte
+	}
+
+	subscriber struct {
+		events  chan event
+		dropped chan struct{}
+	}
+
+	notification struct {
+		Version string      `json:"jsonrpc"`
+		Method  string      `json:"method"`
+		Params  interface{} `json:"params,omitempty"`
+	}
+)
+
+// NewEventStream is
+func NewEventStream(opts ...EventStreamOption) *EventStream {
+	es := &EventStream{
+		options:     defaultEventStreamOptions,
+		subscribers: make(map[*subscriber]struct{}),
+		done:        make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt.applyEventStream(&es.options)
+	}
+	return es
+}
+
+// Publish sends notification of method with params to all subscribers.
+func (es *EventStream) Publish(method string, params interface{}) error {
+	data, err := json.Marshal(&notification{
+		Version: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if es.closed {
+		return ErrEventStreamClosed
+	}
+	es.lastID++
+	ev := event{
+		id:   es.lastID,
+		data: data,
+	}
+	if n := es.options.replaySize; n > 0 {
+		if len(es.replay) < n {
+			es.replay = append(es.replay, ev)
+		} else {
+			es.replay[es.head] = ev
+			es.head = (es.head + 1) % n
+		}
+	}
+	for sub := range es.subscribers {
+		select {
+		case sub.events <- ev:
+		default:
+			// too slow, let it reconnect
+			close(sub.dropped)
+			delete(es.subscribers, sub)
+		}
+	}
+	return nil
+}
+
+// Subscribers returns the number of connected clients.
+func (es *EventStream) Subscribers() int {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	return len(es.subscribers)
+}
+
+// Close disconnects all subscribers. Succeeding Publish fails with ErrEventStreamClosed.
+func (es *EventStream) Close() error {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if !es.closed {
+		es.closed = true
+		close(es.done)
+	}
+	return nil
+}
+
+// subscribe registers new subscriber, and returns the events after lastID in the replay buffer.
+func (es *EventStream) subscribe(lastID uint64, resume bool) (*subscriber, []event) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if es.closed {
+		return nil, nil
+	}
+
+	var replay []event
+	if resume {
+		for i := range es.replay {
+			ev := es.replay[(es.head+i)%len(es.replay)]
+			if ev.id > lastID {
+				replay = append(replay, ev)
+			}
+		}
+	}
+	sub := &subscriber{
+		events:  make(chan event, es.options.bufferSize),
+		dropped: make(chan struct{}),
+	}
+	es.subscribers[sub] = struct{}{}
+	return sub, replay
+}
+
+func (es *EventStream) unsubscribe(sub *subscriber) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	delete(es.subscribers, sub)
+}
+
+func (es *EventStream) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	lastID, err := strconv.ParseUint(req.Header.Get("Last-Event-ID"), 10, 64)
+	sub, replay := es.subscribe(lastID, err == nil)
+	if sub == nil {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	defer es.unsubscribe(sub)
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if d := es.options.clientRetry; d > 0 {
+		fmt.Fprintf(w, "retry: %d\n\n", d.Milliseconds())
+	}
+	for _, ev := range replay {
+		if writeEvent(w, ev) != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	var heartbeat <-chan time.Time
+	if d := es.options.heartbeat; d > 0 {
+		ticker := time.NewTicker(d)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+	for {
+		select {
+		case ev := <-sub.events:
+			if writeEvent(w, ev) != nil {
+				return
+			}
+		case <-heartbeat:
+			if _, err := w.Write([]byte(":\n\n")); err != nil {
+				return
+			}
+		case <-sub.dropped:
+			return
+		case <-es.done:
+			return
+		case <-req.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, ev event) error {
+	_, err := fmt.Fprintf(w, "id: %d\ndata: %s\n\n", ev.id, ev.data)
+	return err
+}
@@ -0,0 +1,133 @@
+package httpjrpc
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daichitakahashi/jrpc"
+	"github.com/stretchr/testify/require"
+)
+
+func waitSubscribers(t *testing.T, es *EventStream, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		return es.Subscribers() == n
+	}, time.Second, time.Millisecond)
+}
+
+func receive(t *testing.T, ch <-chan *jrpc.Request) *jrpc.Request {
+	t.Helper()
+	select {
+	case req := <-ch:
+		return req
+	case <-time.After(time.Second):
+		t.Fatal("notification not received")
+		return nil
+	}
+}
+
+func TestEventStream_ServeHTTP(t *testing.T) {
+	es := NewEventStream(WithClientRetry(10 * time.Millisecond))
+	server := httptest.NewServer(es)
+	defer server.Close()
+
+	require.NoError(t, es.Publish("before", nil))
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "0")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	waitSubscribers(t, es, 1)
+	require.NoError(t, es.Publish("after", []int{1}))
+
+	br := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 8 {
+		line, err := br.ReadString('\n')
+		require.NoError(t, err)
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+	require.Equal(t, []string{
+		"retry: 10",
+		"",
+		"id: 1",
+		`data: {"jsonrpc":"2.0","method":"before"}`,
+		"",
+		"id: 2",
+		`data: {"jsonrpc":"2.0","method":"after","params":[1]}`,
+		"",
+	}, lines)
+
+	require.NoError(t, es.Close())
+	require.Equal(t, ErrEventStreamClosed, es.Publish("closed", nil))
+}
+
+func TestEventStream_Replay(t *testing.T) {
+	es := NewEventStream(WithReplayBuffer(2))
+	for _, method := range []string{"a", "b", "c"} {
+		require.NoError(t, es.Publish(method, nil))
+	}
+
+	// without Last-Event-ID, nothing is replayed
+	sub, replay := es.subscribe(0, false)
+	require.NotNil(t, sub)
+	require.Empty(t, replay)
+	es.unsubscribe(sub)
+
+	// only the last 2 events are kept
+	_, replay = es.subscribe(0, true)
+	require.Len(t, replay, 2)
+	require.EqualValues(t, 2, replay[0].id)
+	require.EqualValues(t, 3, replay[1].id)
+
+	_, replay = es.subscribe(2, true)
+	require.Len(t, replay, 1)
+	require.EqualValues(t, 3, replay[0].id)
+}
+
+func TestSubscribe(t *testing.T) {
+	es := NewEventStream(WithClientRetry(10*time.Millisecond), WithSubscriberBuffer(1))
+	server := httptest.NewServer(es)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := Subscribe(ctx, server.URL, WithHeader("X-Api-Key", "secret"), WithHTTPClient(nil))
+	require.NoError(t, err)
+	waitSubscribers(t, es, 1)
+
+	require.NoError(t, es.Publish("first", map[string]int{"n": 1}))
+	req := receive(t, ch)
+	require.Equal(t, "first", req.Method)
+	require.JSONEq(t, `{"n":1}`, string(*req.Params))
+	require.Equal(t, jrpc.NoID, req.ID)
+
+	// burst of events may disconnect the slow subscriber, but it catches up after reconnecting
+	for _, method := range []string{"second", "third", "fourth"} {
+		require.NoError(t, es.Publish(method, nil))
+	}
+	for _, method := range []string{"second", "third", "fourth"} {
+		require.Equal(t, method, receive(t, ch).Method)
+	}
+
+	cancel()
+	for range ch {
+	}
+}
+
+func TestSubscribe_StatusError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := Subscribe(context.Background(), server.URL)
+	require.IsType(t, &StatusError{}, err)
+}
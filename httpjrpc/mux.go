@@ -0,0 +1,164 @@
+package httpjrpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/daichitakahashi/jrpc"
+)
+
+/*
+TODO:
+
+*/
+
+type (
+	// Mux serves multiple jrpc.Core on different URL paths, such as "/public" and "/admin".
+	// HTTP middleware added by Use is shared by all paths,
+	// while each Core keeps its own interceptors.
+	Mux struct {
+		mu         sync.RWMutex
+		routes     map[string]http.Handler
+		cores      map[string]*jrpc.Core
+		middleware []func(http.Handler) http.Handler
+		handler    http.Handler
+	}
+
+	// MethodInfo describes a method in the discovery view of Mux.
+	// Params and Result are the values given at registration.
+	MethodInfo struct {
+		Name   string      `json:"name"`
+		Params interface{} `json:"params,omitempty"`
+		Result interface{} `json:"result,omitempty"`
+		Safe   bool        `json:"safe,omitempty"`
+	}
+)
+
+// NewMux is
+func NewMux() *Mux {
+	m := &Mux{
+		routes: make(map[string]http.Handler),
+		cores:  make(map[string]*jrpc.Core),
+	}
+	m.handler = http.HandlerFunc(m.route)
+	return m
+}
+
+// Mount serves core on path. The request to the path and its subpaths, such as GET "/admin/user.get",
+// is passed to Repository created with opts.
+func (m *Mux) Mount(path string, core *jrpc.Core, opts ...HandlerOption) {
+	m.handle(path, NewRepository(core, opts...), core)
+}
+
+// Handle serves handler on path, under the middleware of Mux.
+// It can be used for EventStream and DiscoveryHandler.
+func (m *Mux) Handle(path string, handler http.Handler) {
+	m.handle(path, handler, nil)
+}
+
+func (m *Mux) handle(path string, handler http.Handler, core *jrpc.Core) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path = cleanPath(path)
+	m.routes[path] = handler
+	if core != nil {
+		m.cores[path] = core
+	} else {
+		delete(m.cores, path)
+	}
+}
+
+// Use adds HTTP middleware such as authentication, CORS and rate limiting.
+// The middleware added first is the outermost.
+func (m *Mux) Use(middleware ...func(http.Handler) http.Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.middleware = append(m.middleware, middleware...)
+	var h http.Handler = http.HandlerFunc(m.route)
+	for i := len(m.middleware) - 1; i >= 0; i-- {
+		h = m.middleware[i](h)
+	}
+	m.handler = h
+}
+
+func (m *Mux) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	m.mu.RLock()
+	h := m.handler
+	m.mu.RUnlock()
+	h.ServeHTTP(w, req)
+}
+
+// route passes req to the handler mounted on the longest matching path.
+func (m *Mux) route(w http.ResponseWriter, req *http.Request) {
+	m.mu.RLock()
+	var h http.Handler
+	for p := cleanPath(req.URL.Path); ; p = parentPath(p) {
+		if h = m.routes[p]; h != nil || p == "/" {
+			break
+		}
+	}
+	m.mu.RUnlock()
+	if h == nil {
+		http.NotFound(w, req)
+		return
+	}
+	h.ServeHTTP(w, req)
+}
+
+// Describe returns methods of all mounted Cores, keyed by path.
+func (m *Mux) Describe() map[string][]MethodInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	view := make(map[string][]MethodInfo, len(m.cores))
+	for path, core := range m.cores {
+		methods := core.Methods()
+		infos := make([]MethodInfo, 0, len(methods))
+		for name, md := range methods {
+			infos = append(infos, MethodInfo{
+				Name:   name,
+				Params: md.Params,
+				Result: md.Result,
+				Safe:   core.IsSafe(name),
+			})
+		}
+		sort.Slice(infos, func(i, j int) bool {
+			return infos[i].Name < infos[j].Name
+		})
+		view[path] = infos
+	}
+	return view
+}
+
+// DiscoveryHandler returns http.Handler that responds the result of Describe as JSON.
+func (m *Mux) DiscoveryHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		data, err := json.Marshal(m.Describe())
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", contentTypeJSON)
+		w.Write(data)
+	})
+}
+
+// cleanPath returns path that starts with "/" and has no trailing "/".
+func cleanPath(p string) string {
+	return "/" + strings.Trim(p, "/")
+}
+
+func parentPath(p string) string {
+	i := strings.LastIndexByte(p, '/')
+	if i <= 0 {
+		return "/"
+	}
+	return p[:i]
+}
@@ -0,0 +1,81 @@
+package httpjrpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/daichitakahashi/jrpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMux_ServeHTTP(t *testing.T) {
+	public := newCore()
+	admin := jrpc.NewRepository()
+	admin.With(func(ctx context.Context, params *json.RawMessage, info *jrpc.RequestInfo, handler jrpc.Handler) (interface{}, *jrpc.Error) {
+		result, err := handler.ServeJSONRPC(ctx, params)
+		return []interface{}{"admin", result}, err
+	})
+	admin.Register("user.get", jrpc.Safe(jrpc.HandlerFunc(func(context.Context, *json.RawMessage) (interface{}, *jrpc.Error) {
+		return "user", nil
+	})), "", "")
+
+	var passed []string
+	mux := NewMux()
+	mux.Mount("/public", public)
+	mux.Mount("/api/admin/", admin)
+	mux.Handle("/discovery", mux.DiscoveryHandler())
+	mux.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			passed = append(passed, r.URL.Path)
+			if r.Header.Get("X-Api-Key") != "secret" {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Api-Key", "secret")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/public", `{"jsonrpc":"2.0","method":"echo","params":[1],"id":1}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"jsonrpc":"2.0","result":[1],"id":1}`, rec.Body.String())
+
+	// each Core keeps its own interceptors
+	rec = do(http.MethodPost, "/api/admin", `{"jsonrpc":"2.0","method":"user.get","id":1}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"jsonrpc":"2.0","result":["admin","user"],"id":1}`, rec.Body.String())
+
+	// path-based GET under the mounted path
+	rec = do(http.MethodGet, "/api/admin/user.get?id=2", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"jsonrpc":"2.0","result":["admin","user"],"id":2}`, rec.Body.String())
+
+	rec = do(http.MethodPost, "/api", `{"jsonrpc":"2.0","method":"echo","id":1}`)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	// middleware is shared
+	req := httptest.NewRequest(http.MethodPost, "/public", strings.NewReader(`{}`))
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.Equal(t, []string{"/public", "/api/admin", "/api/admin/user.get", "/api", "/public"}, passed)
+
+	rec = do(http.MethodGet, "/discovery", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{
+		"/public": [{"name":"echo"}],
+		"/api/admin": [{"name":"user.get","params":"","result":"","safe":true}]
+	}`, rec.Body.String())
+}
@@ -1,47 +1,349 @@
 package httpjrpc
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
 	"net/http"
+	"path"
+	"strings"
 
 	"github.com/daichitakahashi/jrpc"
 )
 
 /*
 TODO:
-- Repositoryの実装
 
 */
 
-// Repository is
+// Repository is http.Handler that serves JSON-RPC over HTTP POST, and HTTP GET for safe methods.
+// Zero value of options is the same as NewRepository without options.
 type Repository struct {
 	*jrpc.Core
+	options handlerOptions
 }
 
+// NewRepository is
+func NewRepository(core *jrpc.Core, opts ...HandlerOption) *Repository {
+	r := &Repository{
+		Core: core,
+	}
+	for _, opt := range opts {
+		opt.applyHandler(&r.options)
+	}
+	return r
+}
+
+const contentTypeJSON = "application/json"
+
 func (r *Repository) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	if r.options.cors != nil && r.options.cors.apply(w, req) {
+		return // preflight
+	}
+	ctx, rh := r.requestContext(req)
+	req = req.WithContext(ctx)
+	limitWriter := w // http.MaxBytesReader needs the original
+	w = &headerWriter{ResponseWriter: w, header: rh}
+
+	switch req.Method {
+	case http.MethodPost:
+	case http.MethodGet:
+		r.serveGET(w, req)
+		return
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if !acceptsJSON(req.Header.Values("Accept")) {
+		http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		return
+	}
+	if contentType := req.Header.Get("Content-Type"); r.options.attachments && isMultipart(contentType) {
+		r.serveMultipart(w, req, limitWriter)
+		return
+	} else if !isJSON(contentType) {
+		http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	body := r.limit(limitWriter, req.Body)
+	if encoding := req.Header.Get("Content-Encoding"); encoding != "" && encoding != "identity" {
+		zr, err := newDecompressor(encoding, body)
+		if errors.Is(err, errUnsupportedEncoding) {
+			http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+			return
+		} else if err != nil {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		defer zr.Close()
+		body = r.limit(limitWriter, zr)
+	}
+
+	requests, batch, err := decodeRequests(body)
+	switch {
+	case err == io.EOF || err == errTrailingData:
+		// empty body, or more than one JSON value
+		r.writeResponses(w, req, []*jrpc.Response{parseError(err)}, false)
+	case err != nil:
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.handleError(req, err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	case batch && len(requests) == 0:
+		// empty array is answered by a single Response object
+		r.writeResponses(w, req, []*jrpc.Response{invalidRequest()}, false)
+	case batch && r.options.streamingBatch:
+		r.executeStreaming(w, req, requests)
+	default:
+		r.execute(w, req, requests, batch)
+	}
+}
+
+// errTrailingData is returned by decodeRequests when body has data after the message.
+var errTrailingData = errors.New("httpjrpc: invalid data after JSON-RPC message")
+
+// decodeRequests decodes a single or batch request from body.
+// Only whitespace is allowed after the first JSON value.
+func decodeRequests(body io.Reader) ([]*jrpc.Request, bool, error) {
+	dec := jrpc.NewDecoder(body)
+	requests, batch, err := dec.Decode(make([]*jrpc.Request, 0, 10))
+	if err != nil || dec.Err() != nil {
+		return requests, batch, err // Parse error is already in requests
+	}
+	_, _, err = dec.Decode(nil)
+	switch err {
+	case io.EOF:
+		return requests, batch, nil
+	case nil:
+		return nil, false, errTrailingData
+	default:
+		return nil, false, err
+	}
+}
+
+// serveGET serves a single request encoded in URL, such as "/rpc?method=x&params=[1]&id=1".
+// If "method" is omitted, the last segment of the path is used, such as "/rpc/x?params=[1]&id=1".
+// "params" and "id" are JSON text, but id that is not valid JSON is used as string.
+// Only the methods registered with jrpc.Safe can be called.
+// Cache-Control is set by WithCacheControl.
+func (r *Repository) serveGET(w http.ResponseWriter, req *http.Request) {
+	if !acceptsJSON(req.Header.Values("Accept")) {
+		http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		return
+	}
+	query := req.URL.Query()
+	method := query.Get("method")
+	if method == "" {
+		method = path.Base(req.URL.Path)
+	}
+	if !r.IsSafe(method) {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
 
-	// concurrency safeのためには、毎回作り直すべき
-	dec := jrpc.NewDecoder(req.Body)
-	// poolを使うのがいい
-	requests := make([]*jrpc.Request, 0, 10)     // であれば、レスポンスを返す場合には、エラーは返さず、内部に保存しておくこととする？
-	requests, batch, err := dec.Decode(requests) // if dec.Err() != nil { dec.Reset(conn) }
-	if len(requests) == 0 {
+	request := &jrpc.Request{
+		Version: "2.0",
+		Method:  method,
+		ID:      jrpc.NoID,
+	}
+	if v, ok := query["id"]; ok {
+		err := json.Unmarshal([]byte(v[0]), &request.ID)
 		if err != nil {
-			// connection error
-		} else {
-			// invalid request
+			request.ID = jrpc.NewID(v[0])
 		}
 	}
+	if v, ok := query["params"]; ok {
+		if !json.Valid([]byte(v[0])) {
+			r.writeResponses(w, req, []*jrpc.Response{parseError(nil)}, false)
+			return
+		}
+		params := json.RawMessage(v[0])
+		request.Params = &params
+	}
+	if r.options.cacheControl == nil {
+		r.execute(w, req, []*jrpc.Request{request}, false)
+		return
+	}
 
+	resps, err := r.Execute(req.Context(), []*jrpc.Request{request}, false)
+	if err != nil {
+		r.handleError(req, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if len(resps) > 0 {
+		w.Header().Set("Cache-Control", r.options.cacheControl.value(method, resps[0].Error != nil))
+	}
+	r.writeResponses(w, req, resps, false)
+}
+
+func (r *Repository) execute(w http.ResponseWriter, req *http.Request, requests []*jrpc.Request, batch bool) {
 	resps, err := r.Execute(req.Context(), requests, batch)
 	if err != nil {
-		//
+		r.handleError(req, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	r.writeResponses(w, req, resps, batch)
+}
+
+// executeStreaming writes the elements of batch response one by one.
+func (r *Repository) executeStreaming(w http.ResponseWriter, req *http.Request, requests []*jrpc.Request) {
+	flusher, _ := w.(http.Flusher)
+	var buf bytes.Buffer
+	enc := jrpc.NewEncoder(&buf)
+	var written bool
+	err := r.ExecuteFunc(req.Context(), requests, true, func(resp *jrpc.Response) error {
+		buf.Reset()
+		if written {
+			buf.WriteByte(',')
+		} else {
+			buf.WriteByte('[')
+		}
+		err := enc.Encode([]*jrpc.Response{resp}, false)
+		if err != nil {
+			return err
+		}
+		if !written {
+			w.Header().Set("Content-Type", contentTypeJSON)
+			w.WriteHeader(http.StatusOK)
+			written = true
+		}
+		_, err = w.Write(bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}))
+		if err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		r.handleError(req, err)
+		if !written {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+	if !written {
+		// notifications only
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	_, err = w.Write([]byte("]"))
+	if err != nil {
+		r.handleError(req, err)
+	}
+}
+
+func (r *Repository) writeResponses(w http.ResponseWriter, req *http.Request, resps []*jrpc.Response, batch bool) {
+	if len(resps) == 0 {
+		// notifications only
+		w.WriteHeader(http.StatusNoContent)
+		return
 	}
 
-	enc := jrpc.NewEncoder(w)
-	err = enc.Encode(resps, batch)
+	var buf bytes.Buffer
+	err := jrpc.NewEncoder(&buf).Encode(resps, batch)
+	if err != nil {
+		r.handleError(req, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	data := buf.Bytes()
+	if threshold := r.compressionThreshold(); threshold >= 0 {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if encoding := negotiateEncoding(req.Header.Values("Accept-Encoding")); encoding != "" && len(data) >= threshold {
+			data, err = compress(encoding, data)
+			if err != nil {
+				r.handleError(req, err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Encoding", encoding)
+		}
+	}
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(data)
 	if err != nil {
-		//
+		r.handleError(req, err)
+	}
+}
+
+func parseError(err error) *jrpc.Response {
+	return &jrpc.Response{
+		Version: "2.0",
+		Error:   jrpc.ErrParse(err),
+		ID:      jrpc.UnknownID,
+	}
+}
+
+func invalidRequest() *jrpc.Response {
+	return &jrpc.Response{
+		Version: "2.0",
+		Error:   jrpc.ErrInvalidRequest(nil),
+		ID:      jrpc.UnknownID,
+	}
+}
+
+// limit wraps body by http.MaxBytesReader according to WithMaxBodySize.
+func (r *Repository) limit(w http.ResponseWriter, body io.ReadCloser) io.ReadCloser {
+	n := r.options.maxBodySize
+	if n == 0 {
+		n = DefaultMaxBodySize
+	}
+	if n < 0 {
+		return body
+	}
+	return http.MaxBytesReader(w, body, n)
+}
+
+func (r *Repository) compressionThreshold() int {
+	if r.options.compressionThreshold == 0 {
+		return DefaultCompressionThreshold
+	}
+	return r.options.compressionThreshold
+}
+
+func (r *Repository) handleError(req *http.Request, err error) {
+	if r.options.errorHandler != nil {
+		r.options.errorHandler(req, err)
+	}
+}
+
+// isJSON reports whether media type of contentType is application/json.
+// Parameters such as charset are allowed.
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == contentTypeJSON
+}
+
+// acceptsJSON reports whether Accept header values allow application/json.
+// Request without Accept header accepts any media type.
+func acceptsJSON(values []string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil || params["q"] == "0" {
+				continue
+			}
+			switch mediaType {
+			case contentTypeJSON, "application/*", "*/*":
+				return true
+			}
+		}
 	}
+	return false
 }
@@ -0,0 +1,112 @@
+package httpjrpc
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultMaxBodySize is the default limit of the size of request body.
+	DefaultMaxBodySize = 1 << 20
+	// DefaultCompressionThreshold is the default size of response body to be compressed.
+	DefaultCompressionThreshold = 1 << 10
+)
+
+type (
+	handlerOptions struct {
+		maxBodySize          int64
+		compressionThreshold int
+		streamingBatch       bool
+		cacheControl         *cacheControl
+		cors                 *CORS
+		attachments          bool
+		maxUploadSize        int64
+		maxMemory            int64
+		requestContext       []func(ctx context.Context, r *http.Request) context.Context
+		errorHandler         func(r *http.Request, err error)
+	}
+
+	// HandlerOption is
+	HandlerOption interface {
+		applyHandler(opts *handlerOptions)
+	}
+
+	handlerOptionFunc func(opts *handlerOptions)
+)
+
+func (hof handlerOptionFunc) applyHandler(opts *handlerOptions) {
+	hof(opts)
+}
+
+// WithMaxBodySize is
+// Request that has larger body than n is rejected with 413 Request Entity Too Large.
+// The limit is applied to both of compressed and decompressed body.
+// Default is DefaultMaxBodySize. If n < 0, the size is unlimited.
+func WithMaxBodySize(n int64) HandlerOption {
+	return handlerOptionFunc(func(opts *handlerOptions) {
+		opts.maxBodySize = n
+	})
+}
+
+// WithCompressionThreshold is
+// Response body of n bytes or larger is compressed by gzip or deflate according to Accept-Encoding.
+// Default is DefaultCompressionThreshold. If n < 0, responses are never compressed.
+// Compressed request body is always accepted.
+func WithCompressionThreshold(n int) HandlerOption {
+	return handlerOptionFunc(func(opts *handlerOptions) {
+		opts.compressionThreshold = n
+	})
+}
+
+// WithStreamingBatch makes Repository write each element of batch response as soon as it is ready,
+// with chunked transfer encoding. The elements are in the order of completion.
+// Streamed responses are not compressed.
+// If the execution fails after the first element is written, the response is left incomplete.
+func WithStreamingBatch() HandlerOption {
+	return handlerOptionFunc(func(opts *handlerOptions) {
+		opts.streamingBatch = true
+	})
+}
+
+// WithCacheControl makes Repository set Cache-Control to responses of HTTP GET.
+// Successful response is cacheable for the duration of methods[method], or maxAge if the method is not in methods.
+// If the duration is 0 or less, the response is not cached by "no-cache".
+// Error response is never cached by "no-store".
+func WithCacheControl(maxAge time.Duration, methods map[string]time.Duration) HandlerOption {
+	return handlerOptionFunc(func(opts *handlerOptions) {
+		opts.cacheControl = &cacheControl{
+			maxAge:  maxAge,
+			methods: methods,
+		}
+	})
+}
+
+type cacheControl struct {
+	maxAge  time.Duration
+	methods map[string]time.Duration
+}
+
+// value returns Cache-Control for the response of method.
+func (c *cacheControl) value(method string, failed bool) string {
+	if failed {
+		return "no-store"
+	}
+	maxAge, ok := c.methods[method]
+	if !ok {
+		maxAge = c.maxAge
+	}
+	if maxAge <= 0 {
+		return "no-cache"
+	}
+	return "max-age=" + strconv.FormatInt(int64(maxAge/time.Second), 10)
+}
+
+// WithErrorHandler sets the function called with errors that cannot be sent to the client,
+// such as failure of reading request body, Core.Execute and writing response.
+func WithErrorHandler(fn func(r *http.Request, err error)) HandlerOption {
+	return handlerOptionFunc(func(opts *handlerOptions) {
+		opts.errorHandler = fn
+	})
+}
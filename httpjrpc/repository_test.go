@@ -0,0 +1,400 @@
+package httpjrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daichitakahashi/jrpc"
+	"github.com/stretchr/testify/require"
+)
+
+func serve(h http.Handler, method, contentType, accept, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/", strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRepository_ServeHTTP(t *testing.T) {
+	h := NewRepository(newCore())
+
+	testCases := map[string]struct {
+		method, contentType, accept, body string
+
+		status int
+		resp   string
+	}{
+		"call": {
+			method: http.MethodPost, contentType: "application/json; charset=utf-8", accept: "application/json",
+			body:   `{"jsonrpc":"2.0","method":"echo","params":[1],"id":1}`,
+			status: http.StatusOK,
+			resp:   `{"jsonrpc":"2.0","result":[1],"id":1}`,
+		},
+		"batch": {
+			method: http.MethodPost, contentType: "application/json", accept: "*/*",
+			body:   `[{"jsonrpc":"2.0","method":"echo","params":[1],"id":1},{"jsonrpc":"2.0","method":"echo","params":[2]}]`,
+			status: http.StatusOK,
+			resp:   `[{"jsonrpc":"2.0","result":[1],"id":1}]`,
+		},
+		"notification": {
+			method: http.MethodPost, contentType: "application/json",
+			body:   `{"jsonrpc":"2.0","method":"echo","params":[1]}`,
+			status: http.StatusNoContent,
+		},
+		"notification batch": {
+			method: http.MethodPost, contentType: "application/json",
+			body:   `[{"jsonrpc":"2.0","method":"echo","params":[1]},{"jsonrpc":"2.0","method":"echo","params":[2]}]`,
+			status: http.StatusNoContent,
+		},
+		"parse error": {
+			method: http.MethodPost, contentType: "application/json",
+			body:   `{"jsonrpc":"2.0","method":"echo",`,
+			status: http.StatusOK,
+			resp:   `{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`,
+		},
+		"trailing data": {
+			method: http.MethodPost, contentType: "application/json",
+			body:   `{"jsonrpc":"2.0","method":"echo","params":[1],"id":1} garbage`,
+			status: http.StatusOK,
+			resp:   `{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`,
+		},
+		"concatenated": {
+			method: http.MethodPost, contentType: "application/json",
+			body:   `{"jsonrpc":"2.0","method":"echo","params":[1],"id":1}{"jsonrpc":"2.0","method":"echo","params":[2],"id":2}`,
+			status: http.StatusOK,
+			resp:   `{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`,
+		},
+		"trailing whitespace": {
+			method: http.MethodPost, contentType: "application/json",
+			body:   "{\"jsonrpc\":\"2.0\",\"method\":\"echo\",\"params\":[1],\"id\":1}\r\n\t ",
+			status: http.StatusOK,
+			resp:   `{"jsonrpc":"2.0","result":[1],"id":1}`,
+		},
+		"empty batch": {
+			method: http.MethodPost, contentType: "application/json",
+			body:   `[]`,
+			status: http.StatusOK,
+			resp:   `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`,
+		},
+		"empty body": {
+			method: http.MethodPost, contentType: "application/json",
+			status: http.StatusOK,
+			resp:   `{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`,
+		},
+		"method not allowed": {
+			method: http.MethodPut,
+			status: http.StatusMethodNotAllowed,
+		},
+		"unsupported media type": {
+			method: http.MethodPost, contentType: "text/plain",
+			body:   `{"jsonrpc":"2.0","method":"echo","id":1}`,
+			status: http.StatusUnsupportedMediaType,
+		},
+		"not acceptable": {
+			method: http.MethodPost, contentType: "application/json", accept: "text/html, application/json;q=0",
+			body:   `{"jsonrpc":"2.0","method":"echo","id":1}`,
+			status: http.StatusNotAcceptable,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			rec := serve(h, tc.method, tc.contentType, tc.accept, tc.body)
+			require.Equal(t, tc.status, rec.Code)
+			switch tc.status {
+			case http.StatusOK:
+				require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+				require.JSONEq(t, tc.resp, rec.Body.String())
+			case http.StatusNoContent:
+				require.Zero(t, rec.Body.Len())
+			case http.StatusMethodNotAllowed:
+				require.Equal(t, "GET, POST", rec.Header().Get("Allow"))
+			}
+		})
+	}
+}
+
+func TestRepository_ServeHTTP_MaxBodySize(t *testing.T) {
+	var handled []error
+	h := NewRepository(newCore(), WithMaxBodySize(50), WithErrorHandler(func(_ *http.Request, err error) {
+		handled = append(handled, err)
+	}))
+
+	rec := serve(h, http.MethodPost, "application/json", "", `{"jsonrpc":"2.0","method":"echo","params":["too long"],"id":1}`)
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	rec = serve(h, http.MethodPost, "application/json", "", `{"jsonrpc":"2.0","method":"echo","params":[1]}`)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Empty(t, handled)
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("broken body")
+}
+
+func TestRepository_ServeHTTP_ErrorHandler(t *testing.T) {
+	var handled []error
+	h := &Repository{Core: newCore()}
+	h.options.errorHandler = func(_ *http.Request, err error) {
+		handled = append(handled, err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", ioutil.NopCloser(errReader{}))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Len(t, handled, 1)
+	require.EqualError(t, handled[0], "broken body")
+}
+
+func TestRepository_ServeHTTP_GET(t *testing.T) {
+	core := newCore()
+	core.Namespace("user", func(r jrpc.Repository) {
+		r.Register("get", jrpc.Safe(jrpc.HandlerFunc(func(_ context.Context, params *json.RawMessage) (interface{}, *jrpc.Error) {
+			var v interface{}
+			if err := jrpc.UnmarshalParams(params, &v); err != nil {
+				return nil, err
+			}
+			return v, nil
+		})), nil, nil)
+	})
+	h := NewRepository(core)
+
+	testCases := map[string]struct {
+		target string
+		status int
+		resp   string
+	}{
+		"query": {
+			target: "/rpc?method=user.get&params=" + url.QueryEscape(`{"name":"a"}`) + "&id=1",
+			status: http.StatusOK,
+			resp:   `{"jsonrpc":"2.0","result":{"name":"a"},"id":1}`,
+		},
+		"path": {
+			target: "/rpc/user.get?params=" + url.QueryEscape(`[1]`) + "&id=" + url.QueryEscape(`"abc"`),
+			status: http.StatusOK,
+			resp:   `{"jsonrpc":"2.0","result":[1],"id":"abc"}`,
+		},
+		"raw string id": {
+			target: "/rpc/user.get?params=1&id=abc",
+			status: http.StatusOK,
+			resp:   `{"jsonrpc":"2.0","result":1,"id":"abc"}`,
+		},
+		"invalid params": {
+			target: "/rpc/user.get?params=" + url.QueryEscape(`{"name":`) + "&id=1",
+			status: http.StatusOK,
+			resp:   `{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`,
+		},
+		"notification": {
+			target: "/rpc/user.get?params=1",
+			status: http.StatusNoContent,
+		},
+		"unsafe method": {
+			target: "/rpc?method=echo&params=1&id=1",
+			status: http.StatusMethodNotAllowed,
+		},
+		"unknown method": {
+			target: "/rpc/unknown?id=1",
+			status: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.target, nil))
+			require.Equal(t, tc.status, rec.Code)
+			switch tc.status {
+			case http.StatusOK:
+				require.JSONEq(t, tc.resp, rec.Body.String())
+			case http.StatusMethodNotAllowed:
+				require.Equal(t, http.MethodPost, rec.Header().Get("Allow"))
+			}
+		})
+	}
+}
+
+func TestRepository_ServeHTTP_CacheControl(t *testing.T) {
+	core := newCore()
+	echo := jrpc.Safe(jrpc.HandlerFunc(func(_ context.Context, params *json.RawMessage) (interface{}, *jrpc.Error) {
+		var v interface{}
+		if err := jrpc.UnmarshalParams(params, &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}))
+	core.Register("short", echo, nil, nil)
+	core.Register("long", echo, nil, nil)
+	core.Register("never", echo, nil, nil)
+	core.Register("fail", jrpc.Safe(jrpc.HandlerFunc(func(context.Context, *json.RawMessage) (interface{}, *jrpc.Error) {
+		return nil, &jrpc.Error{Code: -32001, Message: "failed"}
+	})), nil, nil)
+	get := func(h http.Handler, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get(NewRepository(core), "/rpc/short?params=1&id=1")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Empty(t, rec.Header().Get("Cache-Control"))
+	require.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+
+	h := NewRepository(core, WithCacheControl(time.Minute, map[string]time.Duration{
+		"long":  time.Hour,
+		"never": 0,
+	}))
+	for target, expected := range map[string]string{
+		"/rpc/short?params=1&id=1":   "max-age=60",
+		"/rpc/long?params=1&id=1":    "max-age=3600",
+		"/rpc/never?params=1&id=1":   "no-cache",
+		"/rpc/fail?id=1":             "no-store",
+		"/rpc/short?params=%7B&id=1": "", // parse error
+		"/rpc/short?params=1":        "", // notification
+	} {
+		rec := get(h, target)
+		require.Equal(t, expected, rec.Header().Get("Cache-Control"), target)
+	}
+}
+
+func TestRepository_ServeHTTP_Compression(t *testing.T) {
+	h := NewRepository(newCore(), WithCompressionThreshold(64))
+	small := `{"jsonrpc":"2.0","method":"echo","params":[1],"id":1}`
+	large := `{"jsonrpc":"2.0","method":"echo","params":["` + strings.Repeat("a", 100) + `"],"id":1}`
+
+	post := func(body []byte, contentEncoding, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if contentEncoding != "" {
+			req.Header.Set("Content-Encoding", contentEncoding)
+		}
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("compressed request", func(t *testing.T) {
+		for _, encoding := range []string{"gzip", "deflate"} {
+			data, err := compress(encoding, []byte(small))
+			require.NoError(t, err)
+			rec := post(data, encoding, "")
+			require.Equal(t, http.StatusOK, rec.Code)
+			require.JSONEq(t, `{"jsonrpc":"2.0","result":[1],"id":1}`, rec.Body.String())
+		}
+	})
+
+	t.Run("unsupported encoding", func(t *testing.T) {
+		rec := post([]byte(small), "br", "")
+		require.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+	})
+
+	t.Run("broken request", func(t *testing.T) {
+		rec := post([]byte(small), "gzip", "")
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("compressed response", func(t *testing.T) {
+		rec := post([]byte(large), "", "deflate;q=0.5, gzip;q=0")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "deflate", rec.Header().Get("Content-Encoding"))
+		require.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+		zr, err := newDecompressor("deflate", rec.Body)
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(zr)
+		require.NoError(t, err)
+		require.Contains(t, string(data), strings.Repeat("a", 100))
+	})
+
+	t.Run("below threshold", func(t *testing.T) {
+		rec := post([]byte(small), "", "gzip")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Empty(t, rec.Header().Get("Content-Encoding"))
+		require.JSONEq(t, `{"jsonrpc":"2.0","result":[1],"id":1}`, rec.Body.String())
+	})
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	require.Equal(t, "gzip", negotiateEncoding([]string{"deflate, gzip"}))
+	require.Equal(t, "deflate", negotiateEncoding([]string{"gzip;q=0", "deflate"}))
+	require.Equal(t, "gzip", negotiateEncoding([]string{"*"}))
+	require.Equal(t, "deflate", negotiateEncoding([]string{"gzip;q=0, *"}))
+	require.Equal(t, "", negotiateEncoding([]string{"gzip;q=0, deflate;q=0, *"}))
+	require.Equal(t, "", negotiateEncoding([]string{"*;q=0"}))
+	require.Equal(t, "", negotiateEncoding([]string{"br, identity"}))
+	require.Equal(t, "", negotiateEncoding(nil))
+}
+
+func TestRepository_ServeHTTP_StreamingBatch(t *testing.T) {
+	core := newCore()
+	release := make(chan struct{})
+	core.Register("block", jrpc.HandlerFunc(func(ctx context.Context, _ *json.RawMessage) (interface{}, *jrpc.Error) {
+		<-release
+		return "released", nil
+	}), nil, nil)
+	server := httptest.NewServer(NewRepository(core, WithStreamingBatch()))
+	defer server.Close()
+
+	body := `[{"jsonrpc":"2.0","method":"block","id":1},{"jsonrpc":"2.0","method":"echo","params":[2],"id":2},{"jsonrpc":"2.0","method":"echo","params":[3]}]`
+	resp, err := http.Post(server.URL, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+
+	// the result of echo arrives before block is released
+	first := make([]byte, len(`[{"jsonrpc":"2.0","result":[2],"id":2}`))
+	_, err = io.ReadFull(resp.Body, first)
+	require.NoError(t, err)
+	require.Equal(t, `[{"jsonrpc":"2.0","result":[2],"id":2}`, string(first))
+
+	close(release)
+	rest, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.JSONEq(t, `[{"jsonrpc":"2.0","result":[2],"id":2},{"jsonrpc":"2.0","result":"released","id":1}]`, string(first)+string(rest))
+
+	// notifications only
+	body = `[{"jsonrpc":"2.0","method":"echo","params":[1]},{"jsonrpc":"2.0","method":"echo","params":[2]}]`
+	resp, err = http.Post(server.URL, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	for body, want := range map[string]string{
+		`[{"jsonrpc":"2.0","method":"echo","params":[1],"id":1}] garbage`: `{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`,
+		`[]`: `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`,
+	} {
+		resp, err = http.Post(server.URL, "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		got, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+		require.JSONEq(t, want, string(got), body)
+	}
+}
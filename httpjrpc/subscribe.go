@@ -0,0 +1,145 @@
+package httpjrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/daichitakahashi/jrpc"
+)
+
+/*
+TODO:
+
+*/
+
+// defaultReconnectDelay is used until the server sends "retry" field.
+const defaultReconnectDelay = time.Second
+
+// Subscribe connects to EventStream at url, and delivers notifications to the returned channel.
+// When the connection is lost, Subscribe reconnects with Last-Event-ID to receive missed notifications,
+// until ctx is done. The channel is closed after ctx is done.
+// If the first connection fails, Subscribe returns the error.
+// WithHTTPClient, WithHeader, WithRequestHook and so on can be used.
+func Subscribe(ctx context.Context, url string, opts ...*HTTPClientOption) (<-chan *jrpc.Request, error) {
+	s := &subscription{
+		url:     url,
+		options: defaultHTTPClientOptions(),
+		delay:   defaultReconnectDelay,
+		ch:      make(chan *jrpc.Request),
+	}
+	for _, opt := range opts {
+		opt.applyHTTP(&s.options)
+	}
+	s.options.resolveClient()
+
+	resp, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	go s.run(ctx, resp)
+	return s.ch, nil
+}
+
+type subscription struct {
+	url     string
+	options httpClientOptions
+	lastID  string
+	delay   time.Duration
+	ch      chan *jrpc.Request
+}
+
+func (s *subscription) connect(ctx context.Context) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "text/event-stream")
+	if s.lastID != "" {
+		req.Header.Set("Last-Event-ID", s.lastID)
+	}
+	for _, ri := range s.options.requestInterceptors {
+		ri(req)
+	}
+
+	resp, err := s.options.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newStatusError(resp)
+	}
+	return resp, nil
+}
+
+func (s *subscription) run(ctx context.Context, resp *http.Response) {
+	defer close(s.ch)
+	for {
+		s.read(ctx, resp.Body)
+		resp.Body.Close()
+
+		for resp = nil; resp == nil; {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(s.delay):
+			}
+			resp, _ = s.connect(ctx)
+		}
+	}
+}
+
+// read parses event stream, and delivers notifications until r reaches EOF or ctx is done.
+func (s *subscription) read(ctx context.Context, r io.Reader) {
+	br := bufio.NewReader(r)
+	var data []byte
+	for {
+		line, err := br.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+		line = bytes.TrimRight(line, "\r\n")
+
+		if len(line) == 0 {
+			// dispatch
+			if len(data) > 0 {
+				var req jrpc.Request
+				if json.Unmarshal(data, &req) == nil {
+					select {
+					case s.ch <- &req:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+			data = data[:0]
+			continue
+		}
+
+		field, value := line, []byte(nil)
+		if i := bytes.IndexByte(line, ':'); i == 0 {
+			continue // comment
+		} else if i > 0 {
+			field, value = line[:i], bytes.TrimPrefix(line[i+1:], []byte(" "))
+		}
+		switch string(field) {
+		case "data":
+			if len(data) > 0 {
+				data = append(data, '\n')
+			}
+			data = append(data, value...)
+		case "id":
+			s.lastID = string(value)
+		case "retry":
+			if ms, err := strconv.Atoi(string(value)); err == nil {
+				s.delay = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
@@ -277,15 +277,21 @@ func (id *ID) UnmarshalJSON(b []byte) error {
 		id.nType = typeUnknown
 		return nil
 	}
+	if len(b) > 0 && b[0] == '"' {
+		// json.Number accepts only string that represents valid number
+		var s string
+		err := json.Unmarshal(b, &s)
+		if err != nil {
+			return err
+		}
+		id.n = json.Number(s)
+		id.nType = typeString
+		return nil
+	}
 	err := json.Unmarshal(b, &id.n)
 	if err != nil {
 		return err
 	}
-
-	if b[0] == '"' {
-		id.nType = typeString
-	} else {
-		id.nType = typeNumber
-	}
+	id.nType = typeNumber
 	return nil
 }
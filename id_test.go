@@ -310,6 +310,11 @@ func TestID_UnmarshalJSON(t *testing.T) {
 			expectedID: NewID("2"),
 			success:    true,
 			desc:       "string ID",
+		}, {
+			src:        []byte(`{"id":"abc-123"}`),
+			expectedID: NewID("abc-123"),
+			success:    true,
+			desc:       "non-numeric string ID",
 		}, {
 			src:        []byte(`{"id":12.3456}`),
 			expectedID: NewID(12.3456),
@@ -0,0 +1,144 @@
+// Package pending provides a table of calls waiting for responses,
+// shared by the transports that read all responses from a single stream.
+package pending
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// ErrDuplicateID is returned by Add when the ID is already used by pending call.
+var ErrDuplicateID = errors.New("jrpc: duplicate ID in pending calls")
+
+type (
+	// Table is a table of calls waiting for responses, keyed by request ID.
+	Table[K comparable] struct {
+		mu    sync.Mutex
+		calls map[K]*Call[K]
+		order []*Call[K]
+		err   error // not nil after connection is lost
+	}
+
+	// Call is a call registered to Table.
+	Call[K comparable] struct {
+		ids  []K
+		done chan struct{}
+		msg  []byte
+		err  error
+	}
+)
+
+// New returns empty Table.
+func New[K comparable]() *Table[K] {
+	return &Table[K]{
+		calls: make(map[K]*Call[K]),
+	}
+}
+
+// Add registers a call that waits for the response to the request that has ids.
+func (p *Table[K]) Add(ids []K) (*Call[K], error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return nil, p.err
+	}
+	for i, id := range ids {
+		if _, ok := p.calls[id]; ok {
+			return nil, fmt.Errorf("%w: %v", ErrDuplicateID, id)
+		}
+		for _, prev := range ids[:i] {
+			if prev == id {
+				return nil, fmt.Errorf("%w: %v", ErrDuplicateID, id)
+			}
+		}
+	}
+	pc := &Call[K]{
+		ids:  ids,
+		done: make(chan struct{}),
+	}
+	for _, id := range ids {
+		p.calls[id] = pc
+	}
+	p.order = append(p.order, pc)
+	return pc, nil
+}
+
+// Wait waits for the response of pc. If ctx is done, pc is removed from p.
+func (p *Table[K]) Wait(ctx context.Context, pc *Call[K]) (io.ReadCloser, error) {
+	select {
+	case <-pc.done:
+		if pc.err != nil {
+			return nil, pc.err
+		}
+		return ioutil.NopCloser(bytes.NewReader(pc.msg)), nil
+	case <-ctx.Done():
+		p.Remove(pc)
+		return nil, ctx.Err()
+	}
+}
+
+// Remove unregisters pc. The response to pc that arrives later is discarded.
+func (p *Table[K]) Remove(pc *Call[K]) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.removeLocked(pc)
+}
+
+func (p *Table[K]) removeLocked(pc *Call[K]) {
+	for _, id := range pc.ids {
+		if p.calls[id] == pc {
+			delete(p.calls, id)
+		}
+	}
+	for i, c := range p.order {
+		if c == pc {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// Deliver passes response msg that has ids to the call that waits for it,
+// and reports whether such call is found.
+// A response that has no valid ID (e.g. Parse error) is passed to the oldest call.
+func (p *Table[K]) Deliver(ids []K, msg []byte) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var pc *Call[K]
+	for _, id := range ids {
+		if pc = p.calls[id]; pc != nil {
+			break
+		}
+	}
+	if pc == nil {
+		if len(ids) > 0 || len(p.order) == 0 {
+			return false
+		}
+		pc = p.order[0]
+	}
+	p.removeLocked(pc)
+	pc.msg = msg
+	close(pc.done)
+	return true
+}
+
+// Fail finishes all pending calls with err. Succeeding Add also fails.
+func (p *Table[K]) Fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err == nil {
+		p.err = err
+	}
+	for _, pc := range p.order {
+		pc.err = p.err
+		close(pc.done)
+	}
+	p.calls = make(map[K]*Call[K])
+	p.order = nil
+}
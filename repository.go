@@ -74,6 +74,16 @@ func (c *Core) Methods() map[string]Metadata {
 	return _copy
 }
 
+// IsSafe reports whether method is registered with the handler marked by Safe.
+func (c *Core) IsSafe(method string) bool {
+	v, ok := c.methods.Load(method)
+	if !ok {
+		return false
+	}
+	_, ok = v.(*Metadata).Handler.(safeHandler)
+	return ok
+}
+
 var _ Repository = (*Core)(nil)
 
 // MethodRepository is
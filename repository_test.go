@@ -75,6 +75,25 @@ func TestMethodRepository_Methods(t *testing.T) {
 	require.Len(t, registered, 0)
 }
 
+func TestCore_IsSafe(t *testing.T) {
+	repo := NewRepository()
+	handler := HandlerFunc(func(_ context.Context, _ *json.RawMessage) (interface{}, *Error) {
+		return "ok", nil
+	})
+	require.NoError(t, repo.Register("unsafe", handler, nil, nil))
+	repo.Namespace("user", func(r Repository) {
+		require.NoError(t, r.Register("get", Safe(handler), nil, nil))
+	})
+
+	require.True(t, repo.IsSafe("user.get"))
+	require.False(t, repo.IsSafe("unsafe"))
+	require.False(t, repo.IsSafe("unknown"))
+
+	resps, err := repo.Execute(context.Background(), []*Request{{Version: "2.0", Method: "user.get", ID: NewID(1)}}, false)
+	require.NoError(t, err)
+	require.Nil(t, resps[0].Error)
+}
+
 func TestMethodRepository_Interceptors(t *testing.T) {
 	repo := NewRepository()
 	firstCnt := &counter{}
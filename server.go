@@ -2,13 +2,36 @@ package jrpc
 
 import (
 	"context"
+	"errors"
 	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // ServeStream is
-func ServeStream(ctx context.Context, stream io.ReadWriter, repository *Core) error {
-	dec := NewDecoder(stream)
-	enc := NewEncoder(stream)
+func ServeStream(ctx context.Context, stream io.ReadWriter, repository *Core, opts ...StreamOption) error {
+	options := defaultStreamOptions
+	for _, opt := range opts {
+		opt.applyStream(&options)
+	}
+	return serveStream(ctx, stream, repository, &options, nil)
+}
+
+// serveStream runs request-response loop.
+// If sc is not nil, the state of sc is updated according to the progress of the loop.
+func serveStream(ctx context.Context, stream io.ReadWriter, repository *Core, options *streamOptions, sc *serverConn) error {
+	var dec *Decoder
+	var enc *Encoder
+	if options.framer != nil {
+		dec = NewFramedDecoder(stream, options.framer)
+		enc = NewFramedEncoder(stream, options.framer)
+	} else {
+		dec = NewDecoder(stream)
+		enc = NewEncoder(stream)
+	}
 	requests := make([]*Request, 0, 10)
 
 	var batch bool
@@ -16,6 +39,9 @@ func ServeStream(ctx context.Context, stream io.ReadWriter, repository *Core) er
 	var resps []*Response
 
 	for {
+		if sc != nil && !sc.setIdle() {
+			return nil // in shutdown
+		}
 		requests, batch, err = dec.Decode(Calibrate(requests, 10))
 		if err != nil {
 			if err == io.EOF {
@@ -23,6 +49,9 @@ func ServeStream(ctx context.Context, stream io.ReadWriter, repository *Core) er
 			}
 			return err
 		}
+		if sc != nil {
+			sc.setActive()
+		}
 
 		resps, err = repository.Execute(ctx, requests, batch)
 		if err != nil {
@@ -30,6 +59,9 @@ func ServeStream(ctx context.Context, stream io.ReadWriter, repository *Core) er
 		}
 
 		if len(resps) == 0 {
+			if options.framer != nil {
+				continue // nothing to be sent for notifications
+			}
 			_, err = stream.Write([]byte{'\n'})
 		} else {
 			err = enc.Encode(resps, batch)
@@ -39,3 +71,309 @@ func ServeStream(ctx context.Context, stream io.ReadWriter, repository *Core) er
 		}
 	}
 }
+
+// ErrServerClosed is returned by Server.Serve after a call to Shutdown or Close.
+var ErrServerClosed = errors.New("jrpc: Server closed")
+
+// Server serves JSON-RPC over connections accepted from net.Listener, such as TCP and Unix socket.
+// Each connection is served by ServeStream loop in its own goroutine.
+type Server struct {
+	repository *Core
+	options    serverOptions
+
+	mu         sync.Mutex
+	listeners  map[net.Listener]struct{}
+	conns      map[*serverConn]struct{}
+	inShutdown int32
+	done       chan struct{}
+	closeOnce  sync.Once
+	sem        chan struct{}
+	wg         sync.WaitGroup
+}
+
+// NewServer is
+func NewServer(repository *Core, opts ...ServerOption) *Server {
+	s := &Server{
+		repository: repository,
+		options:    defaultServerOptions,
+		listeners:  make(map[net.Listener]struct{}),
+		conns:      make(map[*serverConn]struct{}),
+		done:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt.applyServer(&s.options)
+	}
+	if s.options.maxConnections > 0 {
+		s.sem = make(chan struct{}, s.options.maxConnections)
+	}
+	return s
+}
+
+// Serve is
+// It always returns non-nil error. After Shutdown or Close, the returned error is ErrServerClosed.
+func (s *Server) Serve(l net.Listener) error {
+	return s.ServeContext(context.Background(), l)
+}
+
+// ServeContext is
+// ctx is the base of the context of each connection.
+func (s *Server) ServeContext(ctx context.Context, l net.Listener) error {
+	if !s.trackListener(l, true) {
+		return ErrServerClosed
+	}
+	defer s.trackListener(l, false)
+
+	var tempDelay time.Duration
+	for {
+		if s.sem != nil {
+			select {
+			case s.sem <- struct{}{}:
+			case <-s.done:
+				return ErrServerClosed
+			}
+		}
+
+		conn, err := l.Accept()
+		if err != nil {
+			s.release()
+			if s.shuttingDown() {
+				return ErrServerClosed
+			}
+			if te, ok := err.(interface{ Temporary() bool }); ok && te.Temporary() {
+				// same as net/http.Server
+				if tempDelay == 0 {
+					tempDelay = 5 * time.Millisecond
+				} else if tempDelay *= 2; tempDelay > time.Second {
+					tempDelay = time.Second
+				}
+				time.Sleep(tempDelay)
+				continue
+			}
+			return err
+		}
+		tempDelay = 0
+
+		sc := s.newConn(ctx, conn)
+		if sc == nil { // in shutdown
+			conn.Close()
+			s.release()
+			return ErrServerClosed
+		}
+		go sc.serve()
+	}
+}
+
+// Shutdown stops accepting new connections, and waits for in-flight requests to be responded.
+// Idle connections are closed immediately, and active connections are closed after sending response.
+// If ctx expires before all connections are closed, Shutdown returns the context's error.
+func (s *Server) Shutdown(ctx context.Context) error {
+	atomic.StoreInt32(&s.inShutdown, 1)
+	s.closeListeners()
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	for {
+		if s.closeIdleConns() {
+			s.wg.Wait()
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close closes all listeners and connections immediately.
+// Contexts of connections are cancelled.
+func (s *Server) Close() error {
+	atomic.StoreInt32(&s.inShutdown, 1)
+	err := s.closeListeners()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sc := range s.conns {
+		sc.conn.Close()
+		sc.cancel()
+	}
+	return err
+}
+
+const shutdownPollInterval = 50 * time.Millisecond
+
+func (s *Server) shuttingDown() bool {
+	return atomic.LoadInt32(&s.inShutdown) != 0
+}
+
+func (s *Server) trackListener(l net.Listener, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if add {
+		if s.shuttingDown() {
+			return false
+		}
+		s.listeners[l] = struct{}{}
+	} else {
+		delete(s.listeners, l)
+	}
+	return true
+}
+
+func (s *Server) closeListeners() (err error) {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for l := range s.listeners {
+		if cerr := l.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		delete(s.listeners, l)
+	}
+	return err
+}
+
+// closeIdleConns interrupts reading of idle connections, and reports whether all connections are closed.
+// The connection that has started reading a request is left to finish it.
+func (s *Server) closeIdleConns() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sc := range s.conns {
+		sc.mu.Lock()
+		if atomic.CompareAndSwapInt32(&sc.state, connStateIdle, connStateClosing) {
+			sc.conn.SetReadDeadline(aLongTimeAgo)
+		}
+		sc.mu.Unlock()
+	}
+	return len(s.conns) == 0
+}
+
+func (s *Server) release() {
+	if s.sem != nil {
+		<-s.sem
+	}
+}
+
+var aLongTimeAgo = time.Unix(1, 0)
+
+const (
+	connStateIdle int32 = iota
+	connStateReading
+	connStateActive
+	connStateClosing // closed by closeIdleConns
+)
+
+type serverConn struct {
+	server *Server
+	conn   net.Conn
+	ctx    context.Context
+	cancel context.CancelFunc
+	state  int32
+	mu     sync.Mutex // for closing state and read deadline set by closeIdleConns
+}
+
+func (s *Server) newConn(ctx context.Context, conn net.Conn) *serverConn {
+	if s.options.connContext != nil {
+		ctx = s.options.connContext(ctx, conn)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	sc := &serverConn{
+		server: s,
+		conn:   conn,
+		ctx:    ctx,
+		cancel: cancel,
+		state:  connStateActive,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shuttingDown() {
+		cancel()
+		return nil
+	}
+	s.conns[sc] = struct{}{}
+	s.wg.Add(1)
+	return sc
+}
+
+func (sc *serverConn) serve() {
+	s := sc.server
+	defer func() {
+		sc.conn.Close()
+		sc.cancel()
+		s.mu.Lock()
+		delete(s.conns, sc)
+		s.mu.Unlock()
+		s.release()
+		s.wg.Done()
+	}()
+
+	err := serveStream(sc.ctx, sc, s.repository, &s.options.streamOptions, sc)
+	if err == nil || s.options.errorHandler == nil {
+		return
+	}
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return // idle timeout, read timeout or shutdown
+	} else if err == io.ErrUnexpectedEOF {
+		return // closed by peer
+	}
+	s.options.errorHandler(sc.conn, err)
+}
+
+// setIdle is called before waiting the next request, and reports whether the loop should be continued.
+func (sc *serverConn) setIdle() bool {
+	if d := sc.server.options.idleTimeout; d > 0 {
+		sc.conn.SetReadDeadline(time.Now().Add(d))
+	} else {
+		sc.conn.SetReadDeadline(time.Time{})
+	}
+	atomic.StoreInt32(&sc.state, connStateIdle)
+	return !sc.server.shuttingDown()
+}
+
+func (sc *serverConn) setActive() {
+	atomic.StoreInt32(&sc.state, connStateActive)
+}
+
+// Read implements io.Reader.
+// When the first part of the request arrives, idle timeout is switched to read timeout.
+// After closeIdleConns, Read fails without reading. But once the request has arrived,
+// it is read to the end even if closeIdleConns is called at the same time.
+func (sc *serverConn) Read(p []byte) (int, error) {
+	if atomic.LoadInt32(&sc.state) == connStateClosing {
+		return 0, os.ErrDeadlineExceeded
+	}
+	n, err := sc.conn.Read(p)
+	if n == 0 {
+		return n, err
+	}
+	if atomic.CompareAndSwapInt32(&sc.state, connStateIdle, connStateReading) {
+		sc.setReadTimeout()
+	} else if atomic.LoadInt32(&sc.state) == connStateClosing {
+		// closeIdleConns has taken over the connection after the request arrived
+		sc.mu.Lock()
+		if atomic.CompareAndSwapInt32(&sc.state, connStateClosing, connStateReading) {
+			sc.setReadTimeout()
+		}
+		sc.mu.Unlock()
+	}
+	return n, err
+}
+
+func (sc *serverConn) setReadTimeout() {
+	if d := sc.server.options.readTimeout; d > 0 {
+		sc.conn.SetReadDeadline(time.Now().Add(d))
+	} else {
+		sc.conn.SetReadDeadline(time.Time{})
+	}
+}
+
+// Write implements io.Writer.
+func (sc *serverConn) Write(p []byte) (int, error) {
+	if d := sc.server.options.writeTimeout; d > 0 {
+		sc.conn.SetWriteDeadline(time.Now().Add(d))
+	}
+	return sc.conn.Write(p)
+}
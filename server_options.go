@@ -0,0 +1,88 @@
+package jrpc
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+type (
+	serverOptions struct {
+		streamOptions
+		maxConnections int
+		idleTimeout    time.Duration
+		readTimeout    time.Duration
+		writeTimeout   time.Duration
+		connContext    func(ctx context.Context, conn net.Conn) context.Context
+		errorHandler   func(conn net.Conn, err error)
+	}
+
+	// ServerOption is
+	ServerOption interface {
+		applyServer(opts *serverOptions)
+	}
+
+	serverOptionFunc func(opts *serverOptions)
+)
+
+func (sof serverOptionFunc) applyServer(opts *serverOptions) {
+	sof(opts)
+}
+
+func (fo framingOption) applyServer(opts *serverOptions) {
+	fo.applyStream(&opts.streamOptions)
+}
+
+var defaultServerOptions = serverOptions{
+	streamOptions: defaultStreamOptions,
+}
+
+// WithMaxConnections is
+// When the number of connections reaches n, Server stops accepting until one of them is closed.
+// If n <= 0, the number of connections is unlimited.
+func WithMaxConnections(n int) ServerOption {
+	return serverOptionFunc(func(opts *serverOptions) {
+		opts.maxConnections = n
+	})
+}
+
+// WithIdleTimeout is
+// The connection is closed when the next request does not arrive within d.
+func WithIdleTimeout(d time.Duration) ServerOption {
+	return serverOptionFunc(func(opts *serverOptions) {
+		opts.idleTimeout = d
+	})
+}
+
+// WithReadTimeout is
+// The connection is closed when the whole of request is not read within d after it begins to arrive.
+func WithReadTimeout(d time.Duration) ServerOption {
+	return serverOptionFunc(func(opts *serverOptions) {
+		opts.readTimeout = d
+	})
+}
+
+// WithWriteTimeout is
+// The connection is closed when writing response takes longer than d.
+func WithWriteTimeout(d time.Duration) ServerOption {
+	return serverOptionFunc(func(opts *serverOptions) {
+		opts.writeTimeout = d
+	})
+}
+
+// WithConnContext is
+// fn modifies the context used for the connection. It is derived from the context passed to Server.ServeContext.
+// The context is cancelled when the connection is closed.
+func WithConnContext(fn func(ctx context.Context, conn net.Conn) context.Context) ServerOption {
+	return serverOptionFunc(func(opts *serverOptions) {
+		opts.connContext = fn
+	})
+}
+
+// WithConnErrorHandler is
+// fn is called when the connection is closed due to an error, except for timeout and closing by peer.
+func WithConnErrorHandler(fn func(conn net.Conn, err error)) ServerOption {
+	return serverOptionFunc(func(opts *serverOptions) {
+		opts.errorHandler = fn
+	})
+}
@@ -0,0 +1,303 @@
+package jrpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func startServer(t *testing.T, network, address string, opts ...ServerOption) (*Server, net.Listener, chan error) {
+	return startServerWith(t, newMock(), network, address, opts...)
+}
+
+func startServerWith(t *testing.T, core *Core, network, address string, opts ...ServerOption) (*Server, net.Listener, chan error) {
+	l, err := net.Listen(network, address)
+	require.NoError(t, err)
+	s := NewServer(core, opts...)
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(l)
+	}()
+	t.Cleanup(func() {
+		s.Close()
+	})
+	return s, l, served
+}
+
+// roundTrip sends line and reads a line of response
+func roundTrip(conn net.Conn, r *bufio.Reader, line string) (string, error) {
+	_, err := conn.Write([]byte(line + "\n"))
+	if err != nil {
+		return "", err
+	}
+	return r.ReadString('\n')
+}
+
+func TestServer_Serve(t *testing.T) {
+	for _, network := range []string{"tcp", "unix"} {
+		t.Run(network, func(t *testing.T) {
+			address := "127.0.0.1:0"
+			if network == "unix" {
+				address = filepath.Join(t.TempDir(), "jrpc.sock")
+			}
+			_, l, _ := startServer(t, network, address)
+
+			for i := 0; i < 3; i++ {
+				conn, err := net.Dial(network, l.Addr().String())
+				require.NoError(t, err)
+				defer conn.Close()
+
+				r := bufio.NewReader(conn)
+				line, err := roundTrip(conn, r, `{"jsonrpc":"2.0","method":"sum","params":[1,2,3],"id":1}`)
+				require.NoError(t, err)
+				require.Equal(t, `{"jsonrpc":"2.0","result":6,"id":1}`+"\n", line)
+			}
+		})
+	}
+}
+
+func TestServer_ConnContext(t *testing.T) {
+	type key struct{}
+	core := newMock()
+	core.Register("remoteAddr", HandlerFunc(func(ctx context.Context, _ *json.RawMessage) (interface{}, *Error) {
+		return ctx.Value(key{}), nil
+	}), nil, "")
+	_, l, _ := startServerWith(t, core, "tcp", "127.0.0.1:0", WithConnContext(func(ctx context.Context, conn net.Conn) context.Context {
+		return context.WithValue(ctx, key{}, conn.RemoteAddr().String())
+	}))
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	line, err := roundTrip(conn, bufio.NewReader(conn), `{"jsonrpc":"2.0","method":"remoteAddr","id":1}`)
+	require.NoError(t, err)
+	require.Equal(t, `{"jsonrpc":"2.0","result":"`+conn.LocalAddr().String()+`","id":1}`+"\n", line)
+}
+
+func TestServer_MaxConnections(t *testing.T) {
+	_, l, _ := startServer(t, "tcp", "127.0.0.1:0", WithMaxConnections(1))
+
+	conn1, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	r1 := bufio.NewReader(conn1)
+	_, err = roundTrip(conn1, r1, `{"jsonrpc":"2.0","method":"sum","params":[1],"id":1}`)
+	require.NoError(t, err)
+
+	conn2, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn2.Close()
+	_, err = conn2.Write([]byte(`{"jsonrpc":"2.0","method":"sum","params":[2],"id":2}` + "\n"))
+	require.NoError(t, err)
+
+	received := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(conn2).ReadString('\n')
+		received <- line
+	}()
+	select {
+	case <-received:
+		t.Fatal("second connection is served over the limit")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	conn1.Close()
+	select {
+	case line := <-received:
+		require.Equal(t, `{"jsonrpc":"2.0","result":2,"id":2}`+"\n", line)
+	case <-time.After(time.Second):
+		t.Fatal("second connection is not served after first one is closed")
+	}
+}
+
+func TestServer_Timeout(t *testing.T) {
+	t.Run("idle", func(t *testing.T) {
+		_, l, _ := startServer(t, "tcp", "127.0.0.1:0", WithIdleTimeout(50*time.Millisecond))
+		conn, err := net.Dial("tcp", l.Addr().String())
+		require.NoError(t, err)
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		_, err = roundTrip(conn, r, `{"jsonrpc":"2.0","method":"sum","params":[1],"id":1}`)
+		require.NoError(t, err)
+
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, err = r.ReadByte()
+		require.Equal(t, io.EOF, err)
+	})
+
+	t.Run("read", func(t *testing.T) {
+		_, l, _ := startServer(t, "tcp", "127.0.0.1:0", WithReadTimeout(50*time.Millisecond))
+		conn, err := net.Dial("tcp", l.Addr().String())
+		require.NoError(t, err)
+		defer conn.Close()
+
+		// no timeout while idle
+		time.Sleep(100 * time.Millisecond)
+		r := bufio.NewReader(conn)
+		_, err = roundTrip(conn, r, `{"jsonrpc":"2.0","method":"sum","params":[1],"id":1}`)
+		require.NoError(t, err)
+
+		// incomplete request
+		_, err = conn.Write([]byte(`{"jsonrpc":"2.0",`))
+		require.NoError(t, err)
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, err = r.ReadByte()
+		require.Equal(t, io.EOF, err)
+	})
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	s, l, served := startServer(t, "tcp", "127.0.0.1:0")
+
+	idle, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer idle.Close()
+
+	active, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer active.Close()
+
+	// wait.sayHello sleeps for ID milliseconds
+	_, err = active.Write([]byte(`{"jsonrpc":"2.0","method":"wait.sayHello","id":200}` + "\n"))
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- s.Shutdown(context.Background())
+	}()
+
+	// in-flight request is responded
+	line, err := bufio.NewReader(active).ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, `{"jsonrpc":"2.0","result":"hello","id":200}`+"\n", line)
+
+	select {
+	case err := <-shutdown:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Shutdown does not return")
+	}
+	require.Equal(t, ErrServerClosed, <-served)
+
+	// idle connection is closed
+	idle.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = idle.Read(make([]byte, 1))
+	require.Equal(t, io.EOF, err)
+
+	_, err = net.Dial("tcp", l.Addr().String())
+	require.Error(t, err)
+	require.Equal(t, ErrServerClosed, s.Serve(l))
+}
+
+func TestServer_Shutdown_Reading(t *testing.T) {
+	s, l, served := startServer(t, "tcp", "127.0.0.1:0")
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// request arriving in parts is not cut off by Shutdown
+	_, err = conn.Write([]byte(`{"jsonrpc":"2.0","method":`))
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- s.Shutdown(context.Background())
+	}()
+	time.Sleep(2 * shutdownPollInterval)
+	_, err = conn.Write([]byte(`"wait.sayHello","id":1}` + "\n"))
+	require.NoError(t, err)
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, `{"jsonrpc":"2.0","result":"hello","id":1}`+"\n", line)
+	require.NoError(t, <-shutdown)
+	require.Equal(t, ErrServerClosed, <-served)
+}
+
+func TestServerConn_Closing(t *testing.T) {
+	s := NewServer(NewRepository())
+	client, server := net.Pipe()
+	defer client.Close()
+	sc := s.newConn(context.Background(), server)
+	defer sc.conn.Close()
+
+	// closeIdleConns takes over idle connection, then Read refuses to start
+	atomic.StoreInt32(&sc.state, connStateIdle)
+	s.closeIdleConns()
+	require.Equal(t, connStateClosing, atomic.LoadInt32(&sc.state))
+	_, err := sc.Read(make([]byte, 1))
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+
+	// connection reading a request is left as it is
+	atomic.StoreInt32(&sc.state, connStateReading)
+	s.closeIdleConns()
+	require.Equal(t, connStateReading, atomic.LoadInt32(&sc.state))
+}
+
+// hookConn calls afterRead when Read returns data.
+type hookConn struct {
+	net.Conn
+	afterRead func()
+}
+
+func (c *hookConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 && c.afterRead != nil {
+		c.afterRead()
+		c.afterRead = nil
+	}
+	return n, err
+}
+
+func TestServerConn_ClosingAfterArrival(t *testing.T) {
+	s := NewServer(NewRepository())
+	client, server := net.Pipe()
+	defer client.Close()
+	// closeIdleConns runs after the first part of the request arrives, and before Read sees it
+	conn := &hookConn{Conn: server}
+	conn.afterRead = func() {
+		s.closeIdleConns()
+	}
+	sc := s.newConn(context.Background(), conn)
+	defer sc.conn.Close()
+	atomic.StoreInt32(&sc.state, connStateIdle)
+
+	go client.Write([]byte("first, second"))
+	p := make([]byte, 6)
+	n, err := sc.Read(p)
+	require.NoError(t, err)
+	require.Equal(t, "first,", string(p[:n]))
+	require.Equal(t, connStateReading, atomic.LoadInt32(&sc.state))
+
+	// the rest of the request is read
+	n, err = sc.Read(p)
+	require.NoError(t, err)
+	require.Equal(t, " secon", string(p[:n]))
+}
+
+func TestServer_ShutdownTimeout(t *testing.T) {
+	s, l, _ := startServer(t, "tcp", "127.0.0.1:0")
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte(`{"jsonrpc":"2.0","method":"wait.sayHello","id":300}` + "\n"))
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, s.Shutdown(ctx))
+}
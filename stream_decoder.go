@@ -23,6 +23,9 @@ type Decoder struct {
 
 	r   *bufio.Reader
 	dec *json.Decoder
+
+	framer Framer
+	frames FrameReader
 }
 
 // NewDecoder is
@@ -35,6 +38,21 @@ func NewDecoder(r io.Reader) *Decoder {
 	}
 }
 
+// NewFramedDecoder returns Decoder that reads messages framed by f.
+// Different from the stream of JSON values, a parse error of one message does not break the stream.
+func NewFramedDecoder(r io.Reader, f Framer) *Decoder {
+	return &Decoder{
+		framer: f,
+		frames: f.NewFrameReader(r),
+	}
+}
+
+// NewHeaderDecoder returns Decoder that reads messages framed with "Content-Length" header,
+// such as messages of Language Server Protocol.
+func NewHeaderDecoder(r io.Reader) *Decoder {
+	return NewFramedDecoder(r, HeaderFramer{})
+}
+
 // Decode is
 func (d *Decoder) Decode(dst []*Request) (requests []*Request, batch bool, err error) {
 	return d.DecodeContext(nil, dst)
@@ -75,6 +93,9 @@ func (d *Decoder) decode(dst []*Request, done chan struct{}) ([]*Request, bool,
 	if done != nil {
 		defer func() { close(done) }()
 	}
+	if d.frames != nil {
+		return d.decodeFrame(dst)
+	}
 
 	var char byte
 	char, err := d.firstByte()
@@ -117,6 +138,9 @@ func (d *Decoder) decode(dst []*Request, done chan struct{}) ([]*Request, bool,
 }
 
 func (d *Decoder) handleError(err error, dst []*Request, batch bool) ([]*Request, bool, error) {
+	if isTruncated(err) {
+		err = io.EOF
+	}
 	switch err.(type) {
 	case *json.SyntaxError:
 		dst = append(dst[:0], &Request{
@@ -150,6 +174,79 @@ func (d *Decoder) handleError(err error, dst []*Request, batch bool) ([]*Request
 	}
 }
 
+// isTruncated reports whether err is caused by the end of stream in the middle of JSON value.
+// Depending on the version of encoding/json, json.Decoder reports it as *json.SyntaxError instead of io.EOF.
+func isTruncated(err error) bool {
+	se, ok := err.(*json.SyntaxError)
+	return ok && se.Error() == "unexpected end of JSON input"
+}
+
+func (d *Decoder) decodeFrame(dst []*Request) ([]*Request, bool, error) {
+	frame, err := d.frames.ReadFrame()
+	if err != nil {
+		if _, ok := err.(*FrameError); ok {
+			// respond Parse error, and stop reading because next frame is never found
+			d.err = err
+			return append(dst[:0], &Request{
+				Version: "2.0",
+				Method:  rpcParseError,
+				err:     err,
+			}), false, nil
+		}
+		return d.handleError(err, dst, false)
+	}
+	dst, batch := decodeMessage(frame, dst)
+	return dst, batch, nil
+}
+
+// decodeMessage decodes whole of single message.
+// Unlike the stream of JSON values, the boundary of the next message is already known,
+// so parse error does not break the stream.
+func decodeMessage(msg []byte, dst []*Request) ([]*Request, bool) {
+	msg = bytes.TrimLeft(msg, " \t\r\n")
+	batch := len(msg) > 0 && msg[0] == '['
+
+	if batch {
+		var raws []json.RawMessage
+		err := json.Unmarshal(msg, &raws)
+		if err != nil {
+			return append(dst[:0], &Request{
+				Version: "2.0",
+				Method:  rpcParseError,
+				err:     err,
+			}), false
+		}
+		for _, raw := range raws {
+			req := &Request{}
+			err = json.Unmarshal(raw, req)
+			if err != nil {
+				req = &Request{
+					Version: "2.0",
+					Method:  rpcInvalidRequest,
+					err:     err,
+				}
+			}
+			dst = append(dst, req)
+		}
+		return dst, batch
+	}
+
+	req := &Request{}
+	err := json.Unmarshal(msg, req)
+	if err != nil {
+		method := rpcParseError
+		if _, ok := err.(*json.UnmarshalTypeError); ok {
+			method = rpcInvalidRequest
+		}
+		req = &Request{
+			Version: "2.0",
+			Method:  method,
+			err:     err,
+		}
+	}
+	return append(dst, req), batch
+}
+
 // Calibrate is utility
 func Calibrate(requests []*Request, capacity int) []*Request {
 	for i := range requests {
@@ -234,6 +331,10 @@ func isSpace(b byte) bool {
 // Reset not implemented yet
 func (d *Decoder) Reset(r io.Reader) {
 	d.err = nil
+	if d.framer != nil {
+		d.frames = d.framer.NewFrameReader(r)
+		return
+	}
 	d.dirty = false
 	//d.r.Reset(r)
 	d.r = bufio.NewReader(r)
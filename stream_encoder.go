@@ -16,6 +16,9 @@ TODO:
 type Encoder struct {
 	dst *bufio.Writer
 	m   sync.Mutex
+
+	framer Framer
+	frames FrameWriter
 }
 
 // NewEncoder is
@@ -32,6 +35,19 @@ func NewEncoderSize(dst io.Writer, bufSize int) *Encoder {
 	}
 }
 
+// NewFramedEncoder returns Encoder that writes messages framed by f.
+func NewFramedEncoder(dst io.Writer, f Framer) *Encoder {
+	return &Encoder{
+		framer: f,
+		frames: f.NewFrameWriter(dst),
+	}
+}
+
+// NewHeaderEncoder returns Encoder that writes messages framed with "Content-Length" header.
+func NewHeaderEncoder(dst io.Writer) *Encoder {
+	return NewFramedEncoder(dst, HeaderFramer{})
+}
+
 // Encode is
 func (enc *Encoder) Encode(resps []*Response, batch bool) error {
 	return enc.EncodeContext(nil, resps, batch)
@@ -49,6 +65,10 @@ func (enc *Encoder) EncodeContext(ctx context.Context, resps []*Response, batch
 		return nil
 	}
 
+	if enc.frames != nil {
+		return enc.encodeFrame(ctx, buf, resps, batch)
+	}
+
 	if batch {
 		buf.AppendByte('[')
 	} else {
@@ -87,7 +107,30 @@ func (enc *Encoder) EncodeContext(ctx context.Context, resps []*Response, batch
 	return enc.dst.Flush()
 }
 
+func (enc *Encoder) encodeFrame(ctx context.Context, buf *Buffer, resps []*Response, batch bool) (err error) {
+	if ctx != nil {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+	}
+	if batch {
+		err = BatchResponse(resps).encodeTo(buf)
+	} else {
+		err = resps[0].encodeTo(buf)
+	}
+	if err != nil {
+		return
+	}
+	return enc.frames.WriteFrame(buf.Bytes())
+}
+
 // Reset is
 func (enc *Encoder) Reset(dst io.Writer) {
+	if enc.framer != nil {
+		enc.frames = enc.framer.NewFrameWriter(dst)
+		return
+	}
 	enc.dst.Reset(dst)
 }
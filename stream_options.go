@@ -0,0 +1,50 @@
+package jrpc
+
+type (
+	streamOptions struct {
+		framer Framer
+	}
+
+	// StreamOption is
+	StreamOption interface {
+		applyStream(opts *streamOptions)
+	}
+
+	// FramingOption is option for ServeStream, Server and Client.
+	// Server and client that communicate with each other must use the same framing.
+	FramingOption interface {
+		StreamOption
+		ServerOption
+		ClientOption
+	}
+
+	framingOption struct {
+		framer Framer
+	}
+)
+
+func (fo framingOption) applyStream(opts *streamOptions) {
+	opts.framer = fo.framer
+}
+
+func (fo framingOption) apply(opts *clientOptions) {
+	opts.framer = fo.framer
+}
+
+// default is a stream of JSON values, typically delimited by newline
+var defaultStreamOptions = streamOptions{}
+
+// WithFramer is
+// Each message is framed by f instead of being written as a stream of JSON values.
+func WithFramer(f Framer) FramingOption {
+	return framingOption{
+		framer: f,
+	}
+}
+
+// WithHeaderFraming is shorthand for WithFramer(HeaderFramer{}).
+// Each message is preceded by "Content-Length: N\r\n\r\n" header instead of being delimited by newline,
+// as in Language Server Protocol and Debug Adapter Protocol.
+func WithHeaderFraming() FramingOption {
+	return WithFramer(HeaderFramer{})
+}
@@ -0,0 +1,271 @@
+package jrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+
+	"github.com/daichitakahashi/jrpc/internal/pending"
+	"github.com/pkg/errors"
+)
+
+/*
+TODO:
+
+*/
+
+// ErrTransportClosed is returned by calls after StreamTransport is closed.
+var ErrTransportClosed = errors.New("jrpc: transport closed")
+
+// ConnectionLostError is returned to pending calls when the connection is lost
+// before their responses arrive.
+type ConnectionLostError struct {
+	Err error
+}
+
+func (e *ConnectionLostError) Error() string {
+	return "jrpc: connection lost: " + e.Err.Error()
+}
+
+// Unwrap returns the cause of connection loss.
+func (e *ConnectionLostError) Unwrap() error {
+	return e.Err
+}
+
+// StreamTransport is ClientTransport over net.Conn or any other io.ReadWriteCloser.
+// Responses are routed to the callers by their IDs, so that a Client on StreamTransport
+// can be used by concurrent callers.
+// The framing must be the same as that of Client, such as WithHeaderFraming().
+type StreamTransport struct {
+	conn    io.ReadWriteCloser
+	options streamOptions
+	wm      sync.Mutex
+	pending *pendingCalls
+	done    chan struct{}
+
+	cm   sync.Mutex // for SendRequest and ReceivedResponse
+	last *pending.Call[ID]
+}
+
+// NewStreamTransport is
+func NewStreamTransport(conn io.ReadWriteCloser, opts ...StreamOption) *StreamTransport {
+	st := &StreamTransport{
+		conn:    conn,
+		options: defaultStreamOptions,
+		pending: newPendingCalls(),
+		done:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt.applyStream(&st.options)
+	}
+	go st.readLoop()
+	return st
+}
+
+// DialTCP connects to address over TCP.
+func DialTCP(ctx context.Context, address string, opts ...StreamOption) (*StreamTransport, error) {
+	return dialStream(ctx, "tcp", address, opts)
+}
+
+// DialUnix connects to Unix domain socket at path.
+func DialUnix(ctx context.Context, path string, opts ...StreamOption) (*StreamTransport, error) {
+	return dialStream(ctx, "unix", path, opts)
+}
+
+func dialStream(ctx context.Context, network, address string, opts []StreamOption) (*StreamTransport, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewStreamTransport(conn, opts...), nil
+}
+
+func (st *StreamTransport) readLoop() {
+	defer close(st.done)
+
+	next := newMessageReader(st.conn, st.options.framer)
+	for {
+		msg, err := next()
+		if err != nil {
+			st.pending.Fail(&ConnectionLostError{Err: err})
+			st.conn.Close()
+			return
+		}
+		st.pending.deliver(msg)
+	}
+}
+
+// RoundTrip implements CallTransport.
+func (st *StreamTransport) RoundTrip(ctx context.Context, r io.Reader, ids []ID) (io.ReadCloser, error) {
+	var pc *pending.Call[ID]
+	if len(ids) > 0 {
+		var err error
+		pc, err = st.pending.Add(ids)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := st.write(r)
+	if err != nil {
+		if pc != nil {
+			st.pending.Remove(pc)
+		}
+		return nil, err
+	} else if pc == nil {
+		return nil, nil
+	}
+	return st.pending.Wait(ctx, pc)
+}
+
+func (st *StreamTransport) write(r io.Reader) error {
+	st.wm.Lock()
+	defer st.wm.Unlock()
+	_, err := io.Copy(st.conn, r)
+	return err
+}
+
+// SendRequest implements ClientTransport.
+// IDs of the request are read from r to wait the response in ReceivedResponse.
+// Unlike RoundTrip, pair of SendRequest and ReceivedResponse cannot be used concurrently.
+func (st *StreamTransport) SendRequest(ctx context.Context, r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	msg := data
+	if st.options.framer != nil {
+		msg, err = st.options.framer.NewFrameReader(bytes.NewReader(data)).ReadFrame()
+		if err != nil {
+			return err
+		}
+	}
+
+	st.cm.Lock()
+	defer st.cm.Unlock()
+	st.last, err = st.pending.addMessage(msg)
+	if err != nil {
+		return err
+	}
+	err = st.write(bytes.NewReader(data))
+	if err != nil && st.last != nil {
+		st.pending.Remove(st.last)
+		st.last = nil
+	}
+	return err
+}
+
+// ReceivedResponse implements ClientTransport.
+// The response is returned as an unframed message.
+func (st *StreamTransport) ReceivedResponse(ctx context.Context) (recv io.ReadCloser, updated, shouldClose bool, err error) {
+	st.cm.Lock()
+	pc := st.last
+	st.last = nil
+	st.cm.Unlock()
+	if pc == nil {
+		return nil, false, false, nil
+	}
+	recv, err = st.pending.Wait(ctx, pc)
+	return recv, true, true, err
+}
+
+// Close closes the connection. Pending calls fail with ErrTransportClosed.
+func (st *StreamTransport) Close() error {
+	st.pending.Fail(ErrTransportClosed)
+	err := st.conn.Close()
+	<-st.done
+	return err
+}
+
+// Done returns a channel that's closed when the connection is lost or closed.
+func (st *StreamTransport) Done() <-chan struct{} {
+	return st.done
+}
+
+var _ CallTransport = (*StreamTransport)(nil)
+
+// newMessageReader returns function that reads a message at a time from r.
+// Without framer, each JSON value is a message.
+func newMessageReader(r io.Reader, framer Framer) func() ([]byte, error) {
+	if framer != nil {
+		return framer.NewFrameReader(r).ReadFrame
+	}
+	dec := json.NewDecoder(r)
+	return func() ([]byte, error) {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		return raw, err
+	}
+}
+
+// pendingCalls is a table of calls waiting for responses, keyed by request ID.
+type pendingCalls struct {
+	*pending.Table[ID]
+}
+
+func newPendingCalls() *pendingCalls {
+	return &pendingCalls{
+		Table: pending.New[ID](),
+	}
+}
+
+// addMessage registers a call for IDs read from the request message msg.
+// It returns nil without error when msg has no IDs, e.g. notification.
+func (p *pendingCalls) addMessage(msg []byte) (*pending.Call[ID], error) {
+	ids, _ := messageIDs(msg)
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return p.Add(ids)
+}
+
+// deliver passes msg to the call that waits for it, and reports whether such call is found.
+func (p *pendingCalls) deliver(msg []byte) bool {
+	ids, request := messageIDs(msg)
+	if request {
+		return false // not for us
+	}
+	return p.Deliver(ids, msg)
+}
+
+type messageProbe struct {
+	ID     ID      `json:"id"`
+	Method *string `json:"method"`
+}
+
+// messageIDs returns valid IDs that single or batch message contains,
+// and reports whether msg is Request rather than Response.
+func messageIDs(msg []byte) (ids []ID, request bool) {
+	msg = bytes.TrimLeft(msg, " \t\r\n")
+	if len(msg) == 0 {
+		return nil, false
+	}
+	var probes []messageProbe
+	if msg[0] == '[' {
+		if json.Unmarshal(msg, &probes) != nil {
+			return nil, false
+		}
+	} else {
+		var probe messageProbe
+		if json.Unmarshal(msg, &probe) != nil {
+			return nil, false
+		}
+		probes = []messageProbe{probe}
+	}
+
+	ids = make([]ID, 0, len(probes))
+	for _, probe := range probes {
+		if probe.Method != nil {
+			request = true
+		}
+		if probe.ID != NoID && probe.ID != UnknownID {
+			ids = append(ids, probe.ID)
+		}
+	}
+	return ids, request
+}
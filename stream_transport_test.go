@@ -0,0 +1,192 @@
+package jrpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamTransport(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("tcp", func(t *testing.T) {
+		_, l, _ := startServer(t, "tcp", "127.0.0.1:0")
+		st, err := DialTCP(ctx, l.Addr().String())
+		require.NoError(t, err)
+		defer st.Close()
+		testStreamClient(t, NewClient(st))
+	})
+
+	t.Run("unix with header framing", func(t *testing.T) {
+		_, l, _ := startServer(t, "unix", filepath.Join(t.TempDir(), "jrpc.sock"), WithHeaderFraming())
+		st, err := DialUnix(ctx, l.Addr().String(), WithHeaderFraming())
+		require.NoError(t, err)
+		defer st.Close()
+		testStreamClient(t, NewClient(st, WithHeaderFraming()))
+	})
+}
+
+func testStreamClient(t *testing.T, client *Client) {
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var result int
+			err := client.Do(ctx, "sum", []int{i, i}, &result)
+			require.NoError(t, err)
+			require.Equal(t, i*2, result)
+		}(i)
+	}
+	wg.Wait()
+
+	require.NoError(t, client.Notify(ctx, "sum", []int{1}))
+
+	resps, err := client.CallBatch(ctx, BatchRequest{
+		{Version: "2.0", Method: "sum", Params: rawMessage(`[1,2]`), ID: NewID(1)},
+		{Version: "2.0", Method: "sum", Params: rawMessage(`[3]`)},
+		{Version: "2.0", Method: "unknown", ID: NewID(2)},
+	})
+	require.NoError(t, err)
+	require.Len(t, resps, 2)
+	resp, ok := resps.GetFromID(NewID(2))
+	require.True(t, ok)
+	require.Equal(t, ErrorCodeMethodNotFound, resp.Error.Code)
+
+	// SendRequest and ReceivedResponse
+	req, _ := NewRequest("sum", []int{4, 5}, NewID("x"))
+	buf, _ := req.encode()
+	require.NoError(t, client.Transport.SendRequest(ctx, client.frame(buf)))
+	recv, _, _, err := client.Transport.ReceivedResponse(ctx)
+	require.NoError(t, err)
+	var r Response
+	require.NoError(t, json.NewDecoder(recv).Decode(&r))
+	require.Equal(t, NewID("x"), r.ID)
+	require.Equal(t, "9", string(*r.Result))
+}
+
+// fakeServer reads n lines from conn, and passes them to respond.
+func fakeServer(conn net.Conn, n int, respond func(lines []string)) {
+	go func() {
+		r := bufio.NewReader(conn)
+		var lines []string
+		for i := 0; i < n; i++ {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			lines = append(lines, line)
+		}
+		respond(lines)
+	}()
+}
+
+// reverseResponder responds method names as results in reverse order, with unsolicited messages.
+func reverseResponder(conn net.Conn) func(lines []string) {
+	return func(lines []string) {
+		conn.Write([]byte(`{"jsonrpc":"2.0","method":"notify"}` + "\n"))
+		conn.Write([]byte(`{"jsonrpc":"2.0","result":"unknown","id":100}` + "\n"))
+		for i := len(lines) - 1; i >= 0; i-- {
+			var req Request
+			json.Unmarshal([]byte(lines[i]), &req)
+			resp := &Response{Version: "2.0", ID: req.ID}
+			resp.EncodeAndSetResult(req.Method)
+			b, _ := resp.MarshalJSON()
+			conn.Write(append(b, '\n'))
+		}
+	}
+}
+
+func TestStreamTransport_Routing(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	client := NewClient(NewStreamTransport(clientConn), WithIDFactory(&sequentialIDFactory{}))
+	defer client.Close()
+
+	fakeServer(serverConn, 2, reverseResponder(serverConn))
+
+	var wg sync.WaitGroup
+	for _, method := range []string{"first", "second"} {
+		wg.Add(1)
+		go func(method string) {
+			defer wg.Done()
+			var result string
+			require.NoError(t, client.Do(context.Background(), method, nil, &result))
+			require.Equal(t, method, result)
+		}(method)
+	}
+	wg.Wait()
+}
+
+func TestStreamTransport_ConnectionLost(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	st := NewStreamTransport(clientConn)
+	client := NewClient(st)
+
+	fakeServer(serverConn, 1, func([]string) {
+		serverConn.Close()
+	})
+
+	err := client.Do(context.Background(), "sum", []int{1}, nil)
+	var lost *ConnectionLostError
+	require.True(t, errors.As(err, &lost), err)
+
+	select {
+	case <-st.Done():
+	case <-time.After(time.Second):
+		t.Fatal("transport is not done")
+	}
+	err = client.Do(context.Background(), "sum", []int{1}, nil)
+	require.True(t, errors.As(err, &lost), err)
+}
+
+func TestStreamTransport_Close(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+	st := NewStreamTransport(clientConn)
+	client := NewClient(st)
+	fakeServer(serverConn, 1, func([]string) {
+		st.Close() // close while waiting
+	})
+
+	err := client.Do(context.Background(), "sum", []int{1}, nil)
+	require.Equal(t, ErrTransportClosed, err)
+}
+
+func TestStreamTransport_Context(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+	client := NewClient(NewStreamTransport(clientConn))
+	defer client.Close()
+	fakeServer(serverConn, 1, func([]string) {})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := client.Do(ctx, "sum", []int{1}, nil)
+	require.Equal(t, context.DeadlineExceeded, err)
+}
+
+type sequentialIDFactory struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (f *sequentialIDFactory) CreateID() ID {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.n++
+	return NewID(f.n)
+}
+
+func (f *sequentialIDFactory) BatchIDFactory() IDFactory {
+	return f
+}
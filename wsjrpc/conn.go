@@ -0,0 +1,221 @@
+package wsjrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/daichitakahashi/jrpc"
+	"github.com/gorilla/websocket"
+)
+
+/*
+TODO:
+
+*/
+
+// ErrNoConn is returned by Notify when the context is not derived from WebSocket connection.
+var ErrNoConn = errors.New("wsjrpc: no WebSocket connection in context")
+
+// Conn is JSON-RPC connection over WebSocket.
+// Each JSON-RPC message(single object or batch) is carried by a single WebSocket text message.
+type Conn struct {
+	ws *websocket.Conn
+	wm sync.Mutex // WebSocket connection supports one concurrent writer
+	r  io.Reader  // for Read
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newConn(ws *websocket.Conn, options *commonOptions) *Conn {
+	conn := &Conn{
+		ws:     ws,
+		closed: make(chan struct{}),
+	}
+	if options.readLimit > 0 {
+		ws.SetReadLimit(options.readLimit)
+	}
+	if options.pingInterval > 0 {
+		conn.keepalive(options.pingInterval, options.pongWait)
+	}
+	return conn
+}
+
+// keepalive sends ping periodically, and close the connection when pong is not returned within pongWait.
+func (c *Conn) keepalive(pingInterval, pongWait time.Duration) {
+	c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	go func() {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				// WriteControl can be called concurrently with other write methods
+				err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(pongWait))
+				if err != nil {
+					return
+				}
+			case <-c.closed:
+				return
+			}
+		}
+	}()
+}
+
+// ReadFrame implements jrpc.FrameReader.
+// It returns io.EOF when the connection is closed normally.
+func (c *Conn) ReadFrame() ([]byte, error) {
+	_, r, err := c.ws.NextReader()
+	if err != nil {
+		return nil, c.mapError(err)
+	}
+	msg, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, c.mapError(err)
+	}
+	return msg, nil
+}
+
+// WriteFrame implements jrpc.FrameWriter.
+func (c *Conn) WriteFrame(msg []byte) error {
+	c.wm.Lock()
+	defer c.wm.Unlock()
+	return c.ws.WriteMessage(websocket.TextMessage, msg)
+}
+
+// Read implements io.Reader.
+// It reads messages as a stream of JSON values.
+func (c *Conn) Read(p []byte) (int, error) {
+	for {
+		if c.r == nil {
+			_, r, err := c.ws.NextReader()
+			if err != nil {
+				return 0, c.mapError(err)
+			}
+			c.r = r
+		}
+		n, err := c.r.Read(p)
+		if err == io.EOF {
+			c.r = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, c.mapError(err)
+	}
+}
+
+// Write implements io.Writer.
+// p is sent as a single message.
+func (c *Conn) Write(p []byte) (int, error) {
+	err := c.WriteFrame(p)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Notify sends notification to the peer.
+func (c *Conn) Notify(method string, params interface{}) error {
+	req, err := jrpc.NewRequest(method, params, jrpc.NoID)
+	if err != nil {
+		return err
+	}
+	msg, err := req.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	return c.WriteFrame(msg)
+}
+
+// Close sends close message and closes the connection.
+func (c *Conn) Close() error {
+	err := io.ErrClosedPipe
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.ws.WriteControl(
+			websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+			time.Now().Add(time.Second),
+		)
+		err = c.ws.Close()
+	})
+	return err
+}
+
+// Done returns a channel that's closed when Close is called.
+func (c *Conn) Done() <-chan struct{} {
+	return c.closed
+}
+
+func (c *Conn) mapError(err error) error {
+	if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
+		return io.EOF
+	}
+	select {
+	case <-c.closed:
+		if err != nil {
+			return io.EOF // closed by ourselves
+		}
+	default:
+	}
+	return err
+}
+
+var (
+	_ jrpc.FrameReader = (*Conn)(nil)
+	_ jrpc.FrameWriter = (*Conn)(nil)
+	_ io.ReadWriter    = (*Conn)(nil)
+)
+
+// messageFramer makes jrpc.ServeStream read and write each message as a WebSocket message.
+type messageFramer struct{}
+
+func (messageFramer) NewFrameReader(r io.Reader) jrpc.FrameReader {
+	return r.(*Conn)
+}
+
+func (messageFramer) NewFrameWriter(w io.Writer) jrpc.FrameWriter {
+	return w.(*Conn)
+}
+
+type connKey struct{}
+
+// ConnFromContext returns WebSocket connection that the request came from.
+func ConnFromContext(ctx context.Context) (*Conn, bool) {
+	conn, ok := ctx.Value(connKey{}).(*Conn)
+	return conn, ok
+}
+
+// Notify sends notification to the client which the request came from.
+// It can be used in jrpc.Handler served by Repository.
+func Notify(ctx context.Context, method string, params interface{}) error {
+	conn, ok := ConnFromContext(ctx)
+	if !ok {
+		return ErrNoConn
+	}
+	return conn.Notify(method, params)
+}
+
+// isRequest reports whether msg is Request(notification from server) rather than Response.
+func isRequest(msg []byte) bool {
+	msg = bytes.TrimLeft(msg, " \t\r\n")
+	if len(msg) == 0 || msg[0] != '{' {
+		return false
+	}
+	var probe struct {
+		Method *string `json:"method"`
+	}
+	return json.Unmarshal(msg, &probe) == nil && probe.Method != nil
+}
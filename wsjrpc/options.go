@@ -0,0 +1,149 @@
+package wsjrpc
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/daichitakahashi/jrpc"
+	"github.com/gorilla/websocket"
+)
+
+/*
+TODO:
+
+*/
+
+type (
+	commonOptions struct {
+		pingInterval time.Duration
+		pongWait     time.Duration
+		readLimit    int64
+	}
+
+	serverOptions struct {
+		commonOptions
+		checkOrigin func(r *http.Request) bool
+		onConnect   func(conn *Conn)
+	}
+
+	clientOptions struct {
+		commonOptions
+		header   http.Header
+		dialer   *websocket.Dialer
+		onNotify func(req *jrpc.Request)
+	}
+
+	// Option is option for both of Repository and client Transport.
+	// It implements jrpc.ClientOption but do nothing for jrpc.Client.
+	Option struct {
+		jrpc.EmptyClientOption
+		applyServer func(opts *serverOptions)
+		applyClient func(opts *clientOptions)
+	}
+)
+
+var defaultCommonOptions = commonOptions{
+	pingInterval: 30 * time.Second,
+	pongWait:     60 * time.Second,
+}
+
+// WithKeepalive is
+// Ping is sent every pingInterval, and the connection is closed when pong is not received within pongWait.
+// If pingInterval is 0, keepalive is disabled.
+func WithKeepalive(pingInterval, pongWait time.Duration) *Option {
+	apply := func(opts *commonOptions) {
+		opts.pingInterval = pingInterval
+		opts.pongWait = pongWait
+	}
+	return &Option{
+		applyServer: func(opts *serverOptions) {
+			apply(&opts.commonOptions)
+		},
+		applyClient: func(opts *clientOptions) {
+			apply(&opts.commonOptions)
+		},
+	}
+}
+
+// WithReadLimit is
+// The connection is closed when the peer sends a message larger than limit.
+func WithReadLimit(limit int64) *Option {
+	return &Option{
+		applyServer: func(opts *serverOptions) {
+			opts.readLimit = limit
+		},
+		applyClient: func(opts *clientOptions) {
+			opts.readLimit = limit
+		},
+	}
+}
+
+// WithAllowedOrigins is server option
+// Handshake request is accepted only if its Origin header matches one of origins, or "*" is contained.
+// Request without Origin header, which is not sent by browser, is always accepted.
+// By default, only the same origin as Host header is accepted.
+func WithAllowedOrigins(origins ...string) *Option {
+	return WithOriginChecker(func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		for _, o := range origins {
+			if o == "*" || strings.EqualFold(o, origin) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// WithOriginChecker is server option
+// fn reports whether the handshake request is acceptable.
+func WithOriginChecker(fn func(r *http.Request) bool) *Option {
+	return &Option{
+		applyServer: func(opts *serverOptions) {
+			opts.checkOrigin = fn
+		},
+	}
+}
+
+// WithOnConnect is server option
+// fn is called for each connection before serving, and can hold conn to push notifications.
+func WithOnConnect(fn func(conn *Conn)) *Option {
+	return &Option{
+		applyServer: func(opts *serverOptions) {
+			opts.onConnect = fn
+		},
+	}
+}
+
+// WithHeader is client option
+// header is sent with handshake request.
+func WithHeader(header http.Header) *Option {
+	return &Option{
+		applyClient: func(opts *clientOptions) {
+			opts.header = header
+		},
+	}
+}
+
+// WithDialer is client option
+func WithDialer(dialer *websocket.Dialer) *Option {
+	return &Option{
+		applyClient: func(opts *clientOptions) {
+			opts.dialer = dialer
+		},
+	}
+}
+
+// WithNotificationHandler is client option
+// fn is called with each notification sent by server, in the order of arrival.
+// Without this option, notifications from server are discarded.
+func WithNotificationHandler(fn func(req *jrpc.Request)) *Option {
+	return &Option{
+		applyClient: func(opts *clientOptions) {
+			opts.onNotify = fn
+		},
+	}
+}
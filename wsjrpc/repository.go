@@ -0,0 +1,56 @@
+package wsjrpc
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/daichitakahashi/jrpc"
+	"github.com/gorilla/websocket"
+)
+
+/*
+TODO:
+
+*/
+
+// Repository upgrades HTTP connection to WebSocket and serves JSON-RPC over it.
+// Each JSON-RPC message(single object or batch) is carried by a single WebSocket text message.
+type Repository struct {
+	*jrpc.Core
+	upgrader websocket.Upgrader
+	options  serverOptions
+}
+
+// NewRepository is
+func NewRepository(core *jrpc.Core, opts ...*Option) *Repository {
+	r := &Repository{
+		Core: core,
+		options: serverOptions{
+			commonOptions: defaultCommonOptions,
+		},
+	}
+	for _, opt := range opts {
+		if opt.applyServer != nil {
+			opt.applyServer(&r.options)
+		}
+	}
+	r.upgrader.CheckOrigin = r.options.checkOrigin // if nil, same origin is required
+	return r
+}
+
+func (r *Repository) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ws, err := r.upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		return // error response is already sent by Upgrader
+	}
+	conn := newConn(ws, &r.options.commonOptions)
+	defer conn.Close()
+
+	if r.options.onConnect != nil {
+		r.options.onConnect(conn)
+	}
+
+	ctx, cancel := context.WithCancel(context.WithValue(req.Context(), connKey{}, conn))
+	defer cancel()
+	_ = jrpc.ServeStream(ctx, conn, r.Core, jrpc.WithFramer(messageFramer{}))
+}
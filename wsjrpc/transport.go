@@ -0,0 +1,219 @@
+package wsjrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/daichitakahashi/jrpc"
+	"github.com/daichitakahashi/jrpc/internal/pending"
+	"github.com/gorilla/websocket"
+)
+
+/*
+TODO:
+
+*/
+
+// ErrClosed is returned when the connection is already closed.
+var ErrClosed = errors.New("wsjrpc: connection closed")
+
+// Transport is jrpc.CallTransport over WebSocket.
+// Responses are routed to the callers by their IDs, so that it can be used by concurrent callers.
+// Notifications sent by server are separated from responses,
+// and passed to the handler set by WithNotificationHandler.
+type Transport struct {
+	conn    *Conn
+	pending *pending.Table[jrpc.ID]
+	done    chan struct{}
+	err     error
+	options clientOptions
+
+	cm   sync.Mutex // for SendRequest and ReceivedResponse
+	last *pending.Call[jrpc.ID]
+}
+
+// Dial opens WebSocket connection to url. (ws:// or wss://)
+func Dial(ctx context.Context, url string, opts ...*Option) (*Transport, error) {
+	t := &Transport{
+		pending: pending.New[jrpc.ID](),
+		done:    make(chan struct{}),
+		options: clientOptions{
+			commonOptions: defaultCommonOptions,
+			dialer:        websocket.DefaultDialer,
+		},
+	}
+	for _, opt := range opts {
+		if opt.applyClient != nil {
+			opt.applyClient(&t.options)
+		}
+	}
+
+	ws, _, err := t.options.dialer.DialContext(ctx, url, t.options.header)
+	if err != nil {
+		return nil, err
+	}
+	t.conn = newConn(ws, &t.options.commonOptions)
+	go t.readLoop()
+	return t, nil
+}
+
+// NewClient is shorthand for Dial and jrpc.NewClient.
+// *Option can be mixed with jrpc.ClientOption.
+func NewClient(ctx context.Context, url string, opts ...jrpc.ClientOption) (*jrpc.Client, error) {
+	wsOpts := make([]*Option, 0, len(opts))
+	for _, opt := range opts {
+		if o, ok := opt.(*Option); ok {
+			wsOpts = append(wsOpts, o)
+		}
+	}
+	t, err := Dial(ctx, url, wsOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return jrpc.NewClient(t, opts...), nil
+}
+
+func (t *Transport) readLoop() {
+	defer close(t.done)
+	for {
+		msg, err := t.conn.ReadFrame()
+		if err != nil {
+			t.err = err
+			t.pending.Fail(t.closedError())
+			return
+		}
+		if isRequest(msg) {
+			if t.options.onNotify != nil {
+				var req jrpc.Request
+				if json.Unmarshal(msg, &req) == nil {
+					t.options.onNotify(&req)
+				}
+			}
+			continue
+		}
+		t.pending.Deliver(messageIDs(msg), msg) // late response to the cancelled call is discarded
+	}
+}
+
+// RoundTrip implements jrpc.CallTransport.
+func (t *Transport) RoundTrip(ctx context.Context, r io.Reader, ids []jrpc.ID) (io.ReadCloser, error) {
+	msg, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var pc *pending.Call[jrpc.ID]
+	if len(ids) > 0 {
+		pc, err = t.pending.Add(ids)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = t.write(msg)
+	if err != nil {
+		if pc != nil {
+			t.pending.Remove(pc)
+		}
+		return nil, err
+	} else if pc == nil {
+		return nil, nil
+	}
+	return t.pending.Wait(ctx, pc)
+}
+
+// SendRequest sends content of r as a single message.
+// IDs of the request are read from r to wait the response in ReceivedResponse.
+// Unlike RoundTrip, pair of SendRequest and ReceivedResponse cannot be used concurrently.
+func (t *Transport) SendRequest(ctx context.Context, r io.Reader) error {
+	msg, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	t.cm.Lock()
+	defer t.cm.Unlock()
+	t.last = nil
+	if ids := messageIDs(msg); len(ids) > 0 {
+		t.last, err = t.pending.Add(ids)
+		if err != nil {
+			return err
+		}
+	}
+	err = t.write(msg)
+	if err != nil && t.last != nil {
+		t.pending.Remove(t.last)
+		t.last = nil
+	}
+	return err
+}
+
+// ReceivedResponse returns reader of the response to the last request sent by SendRequest.
+// It returns nil when the last request is notification.
+func (t *Transport) ReceivedResponse(ctx context.Context) (recv io.ReadCloser, updated, shouldClose bool, err error) {
+	t.cm.Lock()
+	pc := t.last
+	t.last = nil
+	t.cm.Unlock()
+	if pc == nil {
+		return nil, false, false, nil
+	}
+	recv, err = t.pending.Wait(ctx, pc)
+	return recv, true, true, err
+}
+
+func (t *Transport) write(msg []byte) error {
+	select {
+	case <-t.done:
+		return t.closedError()
+	default:
+	}
+	return t.conn.WriteFrame(msg)
+}
+
+// Close closes the connection. Pending calls fail with ErrClosed.
+func (t *Transport) Close() error {
+	t.pending.Fail(ErrClosed)
+	return t.conn.Close()
+}
+
+func (t *Transport) closedError() error {
+	if t.err == nil || t.err == io.EOF {
+		return ErrClosed
+	}
+	return t.err
+}
+
+type idProbe struct {
+	ID jrpc.ID `json:"id"`
+}
+
+// messageIDs returns valid IDs that single or batch message contains.
+func messageIDs(msg []byte) []jrpc.ID {
+	msg = bytes.TrimLeft(msg, " \t\r\n")
+	var probes []idProbe
+	if len(msg) > 0 && msg[0] == '[' {
+		if json.Unmarshal(msg, &probes) != nil {
+			return nil
+		}
+	} else {
+		var probe idProbe
+		if json.Unmarshal(msg, &probe) != nil {
+			return nil
+		}
+		probes = []idProbe{probe}
+	}
+
+	ids := make([]jrpc.ID, 0, len(probes))
+	for _, probe := range probes {
+		if probe.ID != jrpc.NoID && probe.ID != jrpc.UnknownID {
+			ids = append(ids, probe.ID)
+		}
+	}
+	return ids
+}
+
+var _ jrpc.CallTransport = (*Transport)(nil)
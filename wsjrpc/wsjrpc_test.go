@@ -0,0 +1,173 @@
+package wsjrpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daichitakahashi/jrpc"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T, opts ...*Option) (*httptest.Server, string) {
+	core := jrpc.NewRepository()
+	core.Register("echo", jrpc.HandlerFunc(func(ctx context.Context, params *json.RawMessage) (interface{}, *jrpc.Error) {
+		var s string
+		if err := jrpc.UnmarshalParams(params, &s); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}), "", "")
+	core.Register("sleep", jrpc.HandlerFunc(func(ctx context.Context, params *json.RawMessage) (interface{}, *jrpc.Error) {
+		var ms int
+		if err := jrpc.UnmarshalParams(params, &ms); err != nil {
+			return nil, err
+		}
+		time.Sleep(time.Duration(ms) * time.Millisecond)
+		return "slept", nil
+	}), 0, "")
+	core.Register("subscribe", jrpc.HandlerFunc(func(ctx context.Context, params *json.RawMessage) (interface{}, *jrpc.Error) {
+		for i := 0; i < 3; i++ {
+			if err := Notify(ctx, "tick", i); err != nil {
+				return nil, jrpc.ErrInternal(err)
+			}
+		}
+		return "subscribed", nil
+	}), nil, "")
+
+	server := httptest.NewServer(NewRepository(core, opts...))
+	t.Cleanup(server.Close)
+	return server, "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestWebSocket(t *testing.T) {
+	_, url := newTestServer(t)
+	ctx := context.Background()
+
+	ticks := make(chan int, 3)
+	client, err := NewClient(ctx, url, WithNotificationHandler(func(req *jrpc.Request) {
+		require.Equal(t, "tick", req.Method)
+		var i int
+		require.NoError(t, req.DecodeParams(&i))
+		ticks <- i
+	}))
+	require.NoError(t, err)
+	defer client.Close()
+
+	var result string
+	require.NoError(t, client.Do(ctx, "echo", "hello", &result))
+	require.Equal(t, "hello", result)
+
+	// notification without response never blocks
+	require.NoError(t, client.Notify(ctx, "echo", "ignored"))
+
+	resps, err := client.CallBatch(ctx, jrpc.BatchRequest{
+		{Version: "2.0", Method: "echo", Params: rawMessage(`"a"`), ID: jrpc.NewID(1)},
+		{Version: "2.0", Method: "unknown", ID: jrpc.NewID(2)},
+	})
+	require.NoError(t, err)
+	require.Len(t, resps, 2)
+	resp, ok := resps.GetFromID(jrpc.NewID(2))
+	require.True(t, ok)
+	require.Equal(t, jrpc.ErrorCodeMethodNotFound, resp.Error.Code)
+
+	// server push
+	require.NoError(t, client.Do(ctx, "subscribe", nil, &result))
+	require.Equal(t, "subscribed", result)
+	for i := 0; i < 3; i++ {
+		select {
+		case tick := <-ticks:
+			require.Equal(t, i, tick)
+		case <-time.After(time.Second):
+			t.Fatal("notification not received")
+		}
+	}
+}
+
+func TestWebSocket_Cancelled(t *testing.T) {
+	_, url := newTestServer(t)
+	ctx := context.Background()
+
+	client, err := NewClient(ctx, url)
+	require.NoError(t, err)
+	defer client.Close()
+
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	var result string
+	err = client.Do(timeout, "sleep", 100, &result)
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	// late response to the cancelled call must not be passed to the next call
+	require.NoError(t, client.Do(ctx, "echo", "fast", &result))
+	require.Equal(t, "fast", result)
+	time.Sleep(150 * time.Millisecond)
+	require.NoError(t, client.Do(ctx, "echo", "after", &result))
+	require.Equal(t, "after", result)
+}
+
+func TestWebSocket_Origin(t *testing.T) {
+	server, url := newTestServer(t, WithAllowedOrigins("https://example.com"))
+	ctx := context.Background()
+
+	// without Origin header
+	client, err := NewClient(ctx, url)
+	require.NoError(t, err)
+	client.Close()
+
+	_, err = Dial(ctx, url, WithHeader(http.Header{"Origin": {"https://example.com"}}))
+	require.NoError(t, err)
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://evil.example.com"}})
+	require.Equal(t, websocket.ErrBadHandshake, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// default is same origin only
+	defaultServer, defaultURL := newTestServer(t)
+	_, err = Dial(ctx, defaultURL, WithHeader(http.Header{"Origin": {server.URL}}))
+	require.Error(t, err)
+	_, err = Dial(ctx, defaultURL, WithHeader(http.Header{"Origin": {defaultServer.URL}}))
+	require.NoError(t, err)
+}
+
+func TestWebSocket_Keepalive(t *testing.T) {
+	_, url := newTestServer(t, WithKeepalive(10*time.Millisecond, 50*time.Millisecond))
+	ctx := context.Background()
+
+	client, err := NewClient(ctx, url, WithKeepalive(10*time.Millisecond, 50*time.Millisecond))
+	require.NoError(t, err)
+	defer client.Close()
+
+	// idle longer than pongWait
+	time.Sleep(200 * time.Millisecond)
+
+	var result string
+	require.NoError(t, client.Do(ctx, "echo", "alive", &result))
+	require.Equal(t, "alive", result)
+}
+
+func TestWebSocket_Closed(t *testing.T) {
+	conns := make(chan *Conn, 1)
+	_, url := newTestServer(t, WithOnConnect(func(conn *Conn) {
+		conns <- conn
+	}))
+	ctx := context.Background()
+
+	client, err := NewClient(ctx, url)
+	require.NoError(t, err)
+	(<-conns).Close()
+
+	var result string
+	err = client.Do(ctx, "echo", "hello", &result)
+	require.Error(t, err)
+}
+
+func rawMessage(s string) *json.RawMessage {
+	raw := json.RawMessage(s)
+	return &raw
+}